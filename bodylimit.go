@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errBodyTooLarge is the error body for requests over MAX_BODY_BYTES
+var errBodyTooLarge = apiError{Code: "too_large", Message: "Request body too large"}
+
+// limitedBody wraps an http.MaxBytesReader, remembering whether the limit was hit
+type limitedBody struct {
+	io.ReadCloser
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// isBodyTooLarge reports whether err came from reading past the body size limit
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// bodyLimitMiddleware caps request bodies at maxBytes. Bodies that declare a larger
+// Content-Length are rejected up front with 413; chunked bodies are cut off while the handler
+// reads them, and handlers answer 413 when they see isBodyTooLarge.
+func bodyLimitMiddleware(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := handlerLabel(c)
+
+		if c.Request.ContentLength > maxBytes {
+			getLogger(c).Warn("Request body too large", "content_length", c.Request.ContentLength, "max_body_bytes", maxBytes)
+			httpRequestBodyTooLargeTotal.WithLabelValues(c.Request.Method, path).Inc()
+			respondAPIError(c, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+			return
+		}
+
+		body := &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)}
+		c.Request.Body = body
+
+		c.Next()
+
+		if body.exceeded {
+			getLogger(c).Warn("Request body too large", "max_body_bytes", maxBytes)
+			httpRequestBodyTooLargeTotal.WithLabelValues(c.Request.Method, path).Inc()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	oversized := `{"name": "` + strings.Repeat("x", 200) + `", "price": 1, "level": "debug"}`
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		chunked bool
+	}{
+		{"declared length", http.MethodPost, "/items/", false},
+		{"chunked item", http.MethodPost, "/items/", true},
+		{"chunked log level", http.MethodPut, "/admin/loglevel", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, map[string]string{"MAX_BODY_BYTES": "64"})
+			counter := httpRequestBodyTooLargeTotal.WithLabelValues(tt.method, tt.path)
+			before := counterValue(t, counter)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(oversized))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.chunked {
+				// Hide the length so the body is only cut off while the handler reads it
+				req.Body = io.NopCloser(strings.NewReader(oversized))
+				req.ContentLength = -1
+			}
+			rec := serve(router, req)
+
+			if rec.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413: %s", rec.Code, rec.Body)
+			}
+			if got := decodeError(t, rec.Body); got.Code != "too_large" {
+				t.Errorf("error code = %q, want too_large", got.Code)
+			}
+			if got := counterValue(t, counter) - before; got != 1 {
+				t.Errorf("http_request_body_too_large_total rose by %v, want 1", got)
+			}
+		})
+	}
+}
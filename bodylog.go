@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyCapture keeps the first max bytes written through it, noting whether anything was dropped
+type bodyCapture struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+// Write always reports the full length so it never short-circuits the writer it's teed from
+func (b *bodyCapture) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.max - b.buf.Len(); n > room {
+		p = p[:max(room, 0)]
+		b.truncated = true
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// teeReadCloser copies everything the handler reads from the request body into a capture
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyLogWriter copies the response body into a capture on its way to the client
+type bodyLogWriter struct {
+	gin.ResponseWriter
+	capture *bodyCapture
+}
+
+func (w *bodyLogWriter) Write(p []byte) (int, error) {
+	w.capture.Write(p)
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *bodyLogWriter) WriteString(s string) (int, error) {
+	w.capture.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// newRedactor returns a function masking the values of the given JSON keys, matched without
+// regard to case. It works on text rather than parsed JSON so truncated bodies are masked too.
+func newRedactor(fields []string) func(string) string {
+	if len(fields) == 0 {
+		return func(s string) string { return s }
+	}
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	pattern := regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	return func(s string) string {
+		return pattern.ReplaceAllString(s, `${1}"[REDACTED]"`)
+	}
+}
+
+// bodyLogMiddleware logs request and response bodies through the request logger, capped at
+// maxBytes each and with redactFields masked. Bodies are logged at debug level, or at info when
+// always is set (LOG_BODIES), so raising the log level to debug at runtime turns them on too.
+// Bodies are only captured as the handler reads and writes them; nothing is buffered up front.
+func bodyLogMiddleware(always bool, maxBytes int, redactFields []string) gin.HandlerFunc {
+	level := slog.LevelDebug
+	if always {
+		level = slog.LevelInfo
+	}
+	redact := newRedactor(redactFields)
+
+	return func(c *gin.Context) {
+		logger := getLogger(c)
+		path := c.Request.URL.Path
+		if path == "/metrics" || strings.HasPrefix(path, "/debug/pprof/") || !logger.Enabled(c.Request.Context(), level) {
+			c.Next()
+			return
+		}
+
+		requestBody := &bodyCapture{max: maxBytes}
+		if c.Request.Body != nil {
+			c.Request.Body = teeReadCloser{Reader: io.TeeReader(c.Request.Body, requestBody), Closer: c.Request.Body}
+		}
+		responseBody := &bodyCapture{max: maxBytes}
+		writer := &bodyLogWriter{ResponseWriter: c.Writer, capture: responseBody}
+		c.Writer = writer
+
+		c.Next()
+
+		c.Writer = writer.ResponseWriter
+		logger.Log(c.Request.Context(), level, "HTTP bodies",
+			"request_body", redact(requestBody.buf.String()),
+			"request_body_truncated", requestBody.truncated,
+			"response_body", redact(responseBody.buf.String()),
+			"response_body_truncated", responseBody.truncated,
+		)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultCurrency is the ISO 4217 code from DEFAULT_CURRENCY that item prices are shown in;
+// empty leaves responses with the bare numeric price
+var defaultCurrency string
+
+// currencyCodePattern is the shape of an ISO 4217 currency code
+var currencyCodePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// currencyFormats are the symbols and decimal places of well-known currencies; other codes are
+// written after the amount with two decimals
+var currencyFormats = map[string]struct {
+	symbol   string
+	decimals int
+}{
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"IDR": {"Rp", 0},
+}
+
+// parseCurrency validates a DEFAULT_CURRENCY value, upper-casing it
+func parseCurrency(raw string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(raw))
+	if code != "" && !currencyCodePattern.MatchString(code) {
+		return "", fmt.Errorf("currency must be a three-letter ISO 4217 code, got %q", raw)
+	}
+	return code, nil
+}
+
+// formatPrice renders price with thousands separators in currency, e.g. $1,200.00
+func formatPrice(price float64, currency string) string {
+	format, known := currencyFormats[currency]
+	if !known {
+		format.decimals = 2
+	}
+
+	amount := strconv.FormatFloat(math.Abs(price), 'f', format.decimals, 64)
+	whole, fraction, _ := strings.Cut(amount, ".")
+	var grouped strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	amount = grouped.String()
+	if fraction != "" {
+		amount += "." + fraction
+	}
+
+	sign := ""
+	if price < 0 {
+		sign = "-"
+	}
+	if !known {
+		return sign + amount + " " + currency
+	}
+	return sign + format.symbol + amount
+}
+
+// pricedItem is an Item with its price formatted in defaultCurrency; the extra fields are
+// omitted when no currency is configured
+type pricedItem struct {
+	Item
+	Currency       string `json:"currency,omitempty" xml:"currency,omitempty" example:"USD"`
+	PriceFormatted string `json:"price_formatted,omitempty" xml:"price_formatted,omitempty" example:"$1,200.00"`
+} // @name PricedItem
+
+// withPrice adds the defaultCurrency price fields to item
+func withPrice(item Item) pricedItem {
+	if defaultCurrency == "" {
+		return pricedItem{Item: item}
+	}
+	return pricedItem{Item: item, Currency: defaultCurrency, PriceFormatted: formatPrice(item.Price, defaultCurrency)}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// maxDownstreamBodyBytes caps how much of the downstream response is echoed back
+const maxDownstreamBodyBytes = 1024
+
+var (
+	// downstreamURL is the target of the /downstream demo endpoint, set in main from DOWNSTREAM_URL
+	downstreamURL string
+
+	downstreamClient = newTracedHTTPClient(10 * time.Second)
+)
+
+// newTracedHTTPClient returns an HTTP client that records a client span for each request
+// and injects the trace context (W3C traceparent) into the outgoing headers
+func newTracedHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		Timeout:   timeout,
+	}
+}
+
+// callDownstream demonstrates distributed tracing by calling downstreamURL within the request's trace
+func callDownstream(c *gin.Context) {
+	logger := getLogger(c)
+
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, downstreamURL, nil)
+	if err != nil {
+		logger.Error("Failed to build downstream request", "url", downstreamURL, "error", err)
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	start := time.Now()
+	resp, err := downstreamClient.Do(req)
+	if err != nil {
+		logger.Warn("Downstream request failed", "url", downstreamURL, "error", err)
+		respondError(c, http.StatusBadGateway, "downstream", "Downstream request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownstreamBodyBytes))
+	if err != nil {
+		logger.Warn("Failed to read downstream response", "url", downstreamURL, "error", err)
+		respondError(c, http.StatusBadGateway, "downstream", "Downstream request failed")
+		return
+	}
+
+	duration := time.Since(start)
+	logger.Info("Downstream request completed", "url", downstreamURL, "status", resp.StatusCode, "duration_ms", duration.Milliseconds())
+	respondJSON(c, http.StatusOK, gin.H{
+		"downstream_url":    downstreamURL,
+		"downstream_status": resp.StatusCode,
+		"duration_ms":       duration.Milliseconds(),
+		"body":              string(body),
+	})
+}
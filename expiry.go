@@ -0,0 +1,18 @@
+package main
+
+import "time"
+
+// evictStale deletes the entries of m that stale reports as expired, scanning at most once per
+// interval. Callers run it on access while holding their own lock, so expiring maps need no
+// background goroutine.
+func evictStale[K comparable, V any](m map[K]V, lastScan *time.Time, interval time.Duration, now time.Time, stale func(V) bool) {
+	if now.Sub(*lastScan) <= interval {
+		return
+	}
+	for key, value := range m {
+		if stale(value) {
+			delete(m, key)
+		}
+	}
+	*lastScan = now
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// breakerState is a circuit breaker state, exported as the trace_exporter_state gauge value
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // exporting normally
+	breakerHalfOpen                     // letting one export through to probe the collector
+	breakerOpen                         // dropping spans until the cooldown passes
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half_open"
+	case breakerOpen:
+		return "open"
+	default:
+		return "closed"
+	}
+}
+
+// breakerExporter wraps a span exporter with a circuit breaker. After maxFailures exports fail
+// in a row it opens and drops spans for cooldown, then lets the next export through as a probe:
+// success closes it again, failure reopens it for another cooldown. While open, a down collector
+// costs nothing beyond the dropped spans.
+type breakerExporter struct {
+	sdktrace.SpanExporter
+	maxFailures int
+	cooldown    time.Duration
+	logger      *slog.Logger
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreakerExporter(exporter sdktrace.SpanExporter, maxFailures int, cooldown time.Duration, logger *slog.Logger) *breakerExporter {
+	traceExporterState.Set(float64(breakerClosed))
+	return &breakerExporter{
+		SpanExporter: exporter,
+		maxFailures:  maxFailures,
+		cooldown:     cooldown,
+		logger:       logger,
+	}
+}
+
+func (e *breakerExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state == breakerOpen {
+		if time.Since(e.openedAt) < e.cooldown {
+			return nil
+		}
+		e.setState(breakerHalfOpen)
+	}
+
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	if err == nil {
+		e.failures = 0
+		if e.state != breakerClosed {
+			e.setState(breakerClosed)
+			e.logger.Info("Trace exporter recovered, resuming span export")
+		}
+		return nil
+	}
+
+	e.failures++
+	if e.state == breakerHalfOpen || e.failures >= e.maxFailures {
+		e.setState(breakerOpen)
+		e.openedAt = time.Now()
+		e.logger.Warn("Trace exporter keeps failing, dropping spans until the collector recovers",
+			"consecutive_failures", e.failures, "cooldown", e.cooldown.String(), "error", err)
+	}
+	return err
+}
+
+// setState records a state change in trace_exporter_state; callers hold e.mu
+func (e *breakerExporter) setState(state breakerState) {
+	e.state = state
+	traceExporterState.Set(float64(state))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/connectivity"
+)
+
+// healthCheck is a named dependency check run by /readyz
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// healthCheckResult is the outcome of a single check as reported by /readyz
+type healthCheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+var (
+	healthChecksMu sync.RWMutex
+	healthChecks   []healthCheck
+)
+
+// RegisterHealthCheck adds a readiness check; registering an existing name replaces it
+func RegisterHealthCheck(name string, check func(ctx context.Context) error) {
+	healthChecksMu.Lock()
+	defer healthChecksMu.Unlock()
+
+	for i := range healthChecks {
+		if healthChecks[i].name == name {
+			healthChecks[i].check = check
+			return
+		}
+	}
+	healthChecks = append(healthChecks, healthCheck{name: name, check: check})
+}
+
+// checkCollectorConn fails when the OTLP collector connection is down
+func checkCollectorConn(_ context.Context) error {
+	state := collectorConn.GetState()
+	if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+		// Kick off a reconnect so the next probe can succeed
+		collectorConn.Connect()
+		return fmt.Errorf("connection %s", strings.ToLower(state.String()))
+	}
+	return nil
+}
+
+// runHealthChecks runs every registered check concurrently, each bounded by timeout
+func runHealthChecks(ctx context.Context, timeout time.Duration) map[string]healthCheckResult {
+	healthChecksMu.RLock()
+	checks := make([]healthCheck, len(healthChecks))
+	copy(checks, healthChecks)
+	healthChecksMu.RUnlock()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[string]healthCheckResult, len(checks))
+	)
+	for _, hc := range checks {
+		wg.Add(1)
+		go func(hc healthCheck) {
+			defer wg.Done()
+			defer trackWorker("health_check")()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := hc.check(checkCtx)
+			result := healthCheckResult{
+				Status:     "ok",
+				DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = "failed"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[hc.name] = result
+			mu.Unlock()
+		}(hc)
+	}
+	wg.Wait()
+	return results
+}
+
+// getLiveness answers liveness probes. It checks no dependencies: if the process can serve this,
+// it isn't wedged, and a restart wouldn't fix an outage elsewhere.
+func getLiveness(c *gin.Context) {
+	respondJSON(c, http.StatusOK, gin.H{"status": "alive"})
+}
+
+// getReadiness runs the registered health checks, returning 503 with the failed checks when any is unhealthy
+func getReadiness(c *gin.Context) {
+	logger := getLogger(c)
+
+	results := runHealthChecks(c.Request.Context(), getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second))
+
+	failed := []string{}
+	for name, result := range results {
+		if result.Status != "ok" {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+
+	if len(failed) > 0 {
+		logger.Warn("Readiness check failed", "failed_checks", failed)
+		// Probes and operators need every check's outcome, not just the error envelope
+		recordError(c, "unavailable")
+		respondJSON(c, http.StatusServiceUnavailable, gin.H{
+			"status": "not_ready",
+			"checks": results,
+			"failed": failed,
+		})
+		return
+	}
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"status": "ready",
+		"checks": results,
+	})
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyKeys remembers createItem responses by Idempotency-Key so client retries don't
+// create duplicates; set up in main from IDEMPOTENCY_TTL
+var idempotencyKeys *idempotencyStore
+
+// idempotencyID is an Idempotency-Key within the scope of the client that sent it, so two
+// clients picking the same key never see each other's responses
+type idempotencyID struct {
+	scope string
+	key   string
+}
+
+// newIdempotencyID scopes key to the request's API key when one is sent, else to its client IP.
+// The API key is hashed so it isn't held in memory for the key's lifetime.
+func newIdempotencyID(c *gin.Context, key string) idempotencyID {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		sum := sha256.Sum256([]byte(apiKey))
+		return idempotencyID{scope: "api_key:" + hex.EncodeToString(sum[:]), key: key}
+	}
+	return idempotencyID{scope: "ip:" + c.ClientIP(), key: key}
+}
+
+// idempotencyState is the outcome of claiming an idempotency key
+type idempotencyState int
+
+const (
+	// idempotencyNew means the key is unseen and now claimed by the caller
+	idempotencyNew idempotencyState = iota
+	// idempotencyReplay means the key completed earlier; replay its response
+	idempotencyReplay
+	// idempotencyInFlight means another request holding the key hasn't finished yet
+	idempotencyInFlight
+	// idempotencyMismatch means the key was used earlier with a different request
+	idempotencyMismatch
+)
+
+// idempotencyEntry is a claimed key and, once the request completed, its response
+type idempotencyEntry struct {
+	fingerprint string
+	done        bool
+	status      int
+	location    string
+	body        gin.H
+	expires     time.Time
+}
+
+// idempotencyStore holds idempotency keys in memory, evicting them ttl after they're claimed
+type idempotencyStore struct {
+	mu       sync.Mutex
+	entries  map[idempotencyID]*idempotencyEntry
+	ttl      time.Duration
+	lastScan time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		entries:  make(map[idempotencyID]*idempotencyEntry),
+		ttl:      ttl,
+		lastScan: time.Now(),
+	}
+}
+
+// claim reserves id for a request identified by fingerprint. With idempotencyReplay the
+// returned entry holds the original response; with idempotencyNew the caller must finish with
+// complete or release.
+func (s *idempotencyStore) claim(id idempotencyID, fingerprint string) (idempotencyEntry, idempotencyState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	evictStale(s.entries, &s.lastScan, s.ttl, now, func(entry *idempotencyEntry) bool {
+		return now.After(entry.expires)
+	})
+
+	entry, exists := s.entries[id]
+	if exists && now.After(entry.expires) {
+		delete(s.entries, id)
+		exists = false
+	}
+	switch {
+	case !exists:
+		s.entries[id] = &idempotencyEntry{fingerprint: fingerprint, expires: now.Add(s.ttl)}
+		return idempotencyEntry{}, idempotencyNew
+	case entry.fingerprint != fingerprint:
+		return idempotencyEntry{}, idempotencyMismatch
+	case !entry.done:
+		return idempotencyEntry{}, idempotencyInFlight
+	default:
+		return *entry, idempotencyReplay
+	}
+}
+
+// complete records the response for a claimed key so later retries replay it
+func (s *idempotencyStore) complete(id idempotencyID, status int, location string, body gin.H) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, exists := s.entries[id]; exists {
+		entry.done = true
+		entry.status = status
+		entry.location = location
+		entry.body = body
+	}
+}
+
+// release forgets a claimed key whose request failed, so the client can retry it
+func (s *idempotencyStore) release(id idempotencyID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, id)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateItemIdempotency(t *testing.T) {
+	router := newTestRouter(t, nil)
+	create := func(body, apiKey, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "retry-1")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		req.RemoteAddr = remoteAddr
+		return serve(router, req)
+	}
+	count := func() int {
+		n, _ := store.Count(context.Background())
+		return n
+	}
+	const widget = `{"name": "widget", "price": 10}`
+
+	first := create(widget, "", "192.0.2.1:1234")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first create: status = %d, want 201", first.Code)
+	}
+
+	t.Run("same key and item replays", func(t *testing.T) {
+		rec := create(widget, "", "192.0.2.1:1234")
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want 201", rec.Code)
+		}
+		if rec.Header().Get("Idempotent-Replayed") != "true" {
+			t.Error("Idempotent-Replayed header missing")
+		}
+		if rec.Body.String() != first.Body.String() {
+			t.Errorf("body = %s, want the original %s", rec.Body, first.Body)
+		}
+		if got := count(); got != len(seedItems)+1 {
+			t.Errorf("store holds %d items, want %d", got, len(seedItems)+1)
+		}
+	})
+
+	t.Run("same key with a different item", func(t *testing.T) {
+		rec := create(`{"name": "gadget", "price": 20}`, "", "192.0.2.1:1234")
+		if rec.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("status = %d, want 422", rec.Code)
+		}
+		if got := decodeError(t, rec.Body); got.Code != "validation" {
+			t.Errorf("error code = %q, want validation", got.Code)
+		}
+	})
+
+	t.Run("same key from other clients", func(t *testing.T) {
+		for _, client := range []struct{ apiKey, remoteAddr string }{
+			{"", "192.0.2.2:1234"},
+			{"client-a", "192.0.2.1:1234"},
+		} {
+			before := count()
+			rec := create(widget, client.apiKey, client.remoteAddr)
+			if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+				t.Fatalf("client %+v: status = %d, replayed = %q; want a fresh 201", client, rec.Code, rec.Header().Get("Idempotent-Replayed"))
+			}
+			if got := count(); got != before+1 {
+				t.Errorf("client %+v: store holds %d items, want %d", client, got, before+1)
+			}
+		}
+	})
+}
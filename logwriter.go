@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// maxLogWriteFailures is how many consecutive failed writes disable file logging
+const maxLogWriteFailures = 5
+
+// degradingWriter wraps the log file writer so a full disk or broken file handle doesn't fail
+// every log call. Failed writes are swallowed, and after maxLogWriteFailures in a row the file
+// is dropped for good and onDisable is called once; stdout logging carries on regardless. Every
+// line that doesn't reach the file counts in log_write_errors_total.
+type degradingWriter struct {
+	mu        sync.Mutex
+	w         io.Writer
+	failures  int
+	disabled  bool
+	onDisable func(err error)
+}
+
+func (d *degradingWriter) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.disabled {
+		logWriteErrorsTotal.Inc()
+		return len(p), nil
+	}
+	if _, err := d.w.Write(p); err != nil {
+		logWriteErrorsTotal.Inc()
+		d.failures++
+		if d.failures >= maxLogWriteFailures {
+			d.disabled = true
+			d.onDisable(err)
+		}
+		return len(p), nil
+	}
+	d.failures = 0
+	return len(p), nil
+}
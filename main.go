@@ -2,31 +2,97 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"math/rand"
+	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/propagators/b3"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
+// Build information, injected at build time via
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..."
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildDate = "unknown"
+)
+
+// tracer records the application's own spans below the otelgin server span
+var tracer = otel.Tracer("github.com/dimasyotama/go-observability-dashboard")
+
+// httpDurationBuckets are the http_request_duration_seconds boundaries from HTTP_DURATION_BUCKETS;
+// main logs httpDurationBucketsErr, since the logger doesn't exist yet when metrics are built
+var httpDurationBuckets, httpDurationBucketsErr = parseBuckets(os.Getenv("HTTP_DURATION_BUCKETS"), prometheus.DefBuckets)
+
+// registry holds every metric served at /metrics, so nothing is exposed just by being
+// registered on the global default registry
+var registry = prometheus.NewRegistry()
+
 // Prometheus metrics
 var (
+	buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "Build information about the running binary, always 1",
+		},
+		[]string{"version", "commit", "goversion"},
+	)
+
 	httpRequestsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
@@ -35,11 +101,21 @@ var (
 		[]string{"method", "handler", "status"},
 	)
 
+	// httpResponsesByClass mirrors httpRequestsTotal with a single low-cardinality label, for
+	// cheap status class panels and alerts
+	httpResponsesByClass = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_responses_by_class_total",
+			Help: "Total number of HTTP responses by status class (2xx, 3xx, 4xx, 5xx)",
+		},
+		[]string{"class"},
+	)
+
 	httpRequestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "http_request_duration_seconds",
 			Help:    "HTTP request duration in seconds",
-			Buckets: prometheus.DefBuckets,
+			Buckets: httpDurationBuckets,
 		},
 		[]string{"method", "handler"},
 	)
@@ -66,311 +142,2709 @@ var (
 			Buckets: []float64{0, 1, 5, 10, 25, 50},
 		},
 	)
-)
 
-// Item represents a product item
-type Item struct {
-	Name    string  `json:"name" binding:"required"`
-	Price   float64 `json:"price" binding:"required"`
-	IsOffer *bool   `json:"is_offer,omitempty"`
-}
+	searchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "search_duration_seconds",
+			Help:    "Time spent filtering and sorting the search catalog, excluding HTTP overhead",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"has_results"},
+	)
 
-// Fake database
-var (
-	fakeItemsDB = map[int]map[string]interface{}{
-		1: {"name": "laptop", "price": 1200.0},
-		2: {"name": "mouse", "price": 25.0},
-		3: {"name": "keyboard", "price": 75.0},
-	}
+	searchEmptyResultsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "search_empty_results_total",
+			Help: "Total number of search requests that returned no results",
+		},
+	)
 
-	allItems = []map[string]interface{}{
-		{"name": "laptop", "price": 1200.0},
-		{"name": "mouse", "price": 25.0},
-		{"name": "keyboard", "price": 75.0},
-		{"name": "monitor", "price": 300.0},
-		{"name": "webcam", "price": 50.0},
-	}
-)
+	httpRequestSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_size_bytes",
+			Help:    "HTTP request body size in bytes",
+			Buckets: []float64{100, 1000, 10000, 100000, 1000000},
+		},
+		[]string{"method", "handler"},
+	)
 
-func init() {
-	// Register Prometheus metrics
-	prometheus.MustRegister(httpRequestsTotal)
-	prometheus.MustRegister(httpRequestDuration)
-	prometheus.MustRegister(itemOperationsTotal)
-	prometheus.MustRegister(searchRequestsTotal)
-	prometheus.MustRegister(searchResultsCount)
-}
+	httpResponseSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "HTTP response body size in bytes",
+			Buckets: []float64{100, 1000, 10000, 100000, 1000000},
+		},
+		[]string{"method", "handler"},
+	)
 
-// initTracer initializes OpenTelemetry tracer
-func initTracer() (*sdktrace.TracerProvider, error) {
-	ctx := context.Background()
+	httpRequestsInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_requests_in_flight",
+			Help: "Current number of HTTP requests being served",
+		},
+	)
 
-	conn, err := grpc.DialContext(
-		ctx,
-		"otel-collector:4317",
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
+	httpRateLimitedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "http_rate_limited_total",
+			Help: "Total number of HTTP requests rejected by the rate limiter",
+		},
 	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create gRPC connection: %w", err)
-	}
 
-	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
-	}
+	httpPanicsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_panics_total",
+			Help: "Total number of panics recovered while handling HTTP requests",
+		},
+		[]string{"method", "handler"},
+	)
 
-	res, err := resource.New(ctx,
-		resource.WithAttributes(
-			semconv.ServiceName("the-app"),
-		),
+	httpRequestTimeoutsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_request_timeouts_total",
+			Help: "Total number of HTTP requests that exceeded REQUEST_TIMEOUT",
+		},
+		[]string{"method", "handler"},
 	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create resource: %w", err)
-	}
 
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
-		sdktrace.WithResource(res),
+	httpRequestBodyTooLargeTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_request_body_too_large_total",
+			Help: "Total number of HTTP requests rejected for exceeding MAX_BODY_BYTES",
+		},
+		[]string{"method", "handler"},
 	)
 
-	otel.SetTracerProvider(tp)
-	return tp, nil
-}
+	appErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "app_errors_total",
+			Help: "Total number of error responses by handler and error category",
+		},
+		[]string{"handler", "category"},
+	)
 
-// newSlogLogger creates a new structured logger that writes to both stdout and file
-func newSlogLogger() (*slog.Logger, error) {
-	// Create logs directory if it doesn't exist
-	if err := os.MkdirAll("/app/logs", 0755); err != nil {
-		return nil, fmt.Errorf("failed to create logs directory: %w", err)
-	}
+	traceExporterState = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "trace_exporter_state",
+			Help: "Circuit breaker state of the OTLP trace exporter: 0 closed, 1 half-open (probing), 2 open (dropping spans)",
+		},
+	)
 
-	// Open log file
-	logFile, err := os.OpenFile("/app/logs/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
-	}
+	logWriteErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "log_write_errors_total",
+			Help: "Total number of log lines that failed to reach the log file, including those dropped after file logging was disabled",
+		},
+	)
 
-	// Create multi-writer (write to both stdout and file)
-	multiWriter := io.MultiWriter(os.Stdout, logFile)
+	// itemsTotal reads the store on every scrape, so it can't drift from the stored items
+	itemsTotal = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "items_total",
+			Help: "Current number of stored items, NaN when the store can't be read",
+		},
+		func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			count, err := store.Count(ctx)
+			if err != nil {
+				return math.NaN()
+			}
+			return float64(count)
+		},
+	)
 
-	// Create JSON handler
-	handler := slog.NewJSONHandler(multiWriter, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})
+	appActiveWorkers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "app_active_workers",
+			Help: "Goroutines currently running the application's own background work",
+		},
+		[]string{"worker"},
+	)
+
+	// itemsByPriceBucket is recomputed from the store after each write rather than on scrape
+	itemsByPriceBucket = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "items_by_price_bucket",
+			Help: "Current number of stored items in each price range",
+		},
+		[]string{"bucket"},
+	)
+
+	searchPageSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "search_page_size",
+			Help:    "Histogram of the page size (limit) requested by search clients",
+			Buckets: []float64{1, 5, 10, 20, 50, 100},
+		},
+	)
+)
+
+// maxIDRangeWidth caps how many IDs a single range query may span
+const maxIDRangeWidth = 1000
+
+// maxBatchSize caps how many items a single batch create may contain
+const maxBatchSize = 100
+
+// maxSearchQueryLength caps the search name query, in characters; set from SEARCH_MAX_QUERY_LENGTH
+var maxSearchQueryLength = 256
 
-	return slog.New(handler), nil
+// maxSimulatedLatency caps the delay /slow can be asked for
+const maxSimulatedLatency = 10 * time.Second
+
+// Limits for the /burn CPU load simulation
+const (
+	maxBurnDuration = 10 * time.Second
+	maxBurnWorkers  = 64
+)
+
+// priceBuckets are the items_by_price_bucket ranges; each covers prices below its upper bound
+// and at or above the previous one
+var priceBuckets = []struct {
+	label string
+	upper float64
+}{
+	{"0-50", 50},
+	{"50-100", 100},
+	{"100-500", 500},
+	{"500+", math.Inf(1)},
 }
 
-// structuredLogMiddleware adds a structured logger (slog) to the context
-func structuredLogMiddleware(logger *slog.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		requestLogger := logger
+// priceBucketsMu serialises refreshes so a slow one can't overwrite a newer result
+var priceBucketsMu sync.Mutex
 
-		span := trace.SpanFromContext(c.Request.Context())
-		if span.SpanContext().IsValid() {
-			requestLogger = logger.With(
-				"trace_id", span.SpanContext().TraceID().String(),
-				"span_id", span.SpanContext().SpanID().String(),
-			)
-		}
+// Pagination defaults
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
 
-		c.Set("logger", requestLogger)
-		c.Next()
+// Item represents a product item; ID is assigned by the store and ignored on input
+type Item struct {
+	XMLName xml.Name `json:"-" xml:"item"`
+	ID      int      `json:"item_id" xml:"item_id"`
+	Name    string   `json:"name" xml:"name" binding:"required"`
+	Price   float64  `json:"price" xml:"price" binding:"required"`
+	IsOffer *bool    `json:"is_offer,omitempty" xml:"is_offer,omitempty"`
+} // @name Item
+
+// validate checks the business rules binding tags can't express clearly
+func (i Item) validate() error {
+	if i.Price <= 0 {
+		return fieldError{Field: "price", Message: "must be greater than 0"}
 	}
+	return nil
 }
 
-// prometheusMiddleware records metrics for each request
-func prometheusMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-		c.Next()
+// itemPatch is a PATCH body; nil fields were absent and leave the stored value unchanged
+type itemPatch struct {
+	Name    *string  `json:"name"`
+	Price   *float64 `json:"price"`
+	IsOffer *bool    `json:"is_offer"`
+} // @name ItemPatch
 
-		duration := time.Since(start).Seconds()
-		status := strconv.Itoa(c.Writer.Status())
-		path := c.FullPath()
-		if path == "" {
-			path = "none"
+// apply returns item with the fields present in the patch overwritten
+func (p itemPatch) apply(item Item) Item {
+	if p.Name != nil {
+		item.Name = *p.Name
+	}
+	if p.Price != nil {
+		item.Price = *p.Price
+	}
+	if p.IsOffer != nil {
+		item.IsOffer = p.IsOffer
+	}
+	return item
+}
+
+// fieldError describes why a single request body field is invalid
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+} // @name FieldError
+
+func (e fieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// fieldErrors translates a bind or validation error into per-field errors, returning false
+// when err means the body couldn't be decoded at all
+func fieldErrors(err error) ([]fieldError, bool) {
+	var validationErrs validator.ValidationErrors
+	var fieldErr fieldError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &validationErrs):
+		errs := make([]fieldError, 0, len(validationErrs))
+		for _, e := range validationErrs {
+			message := fmt.Sprintf("failed the %s check", e.Tag())
+			if e.Tag() == "required" {
+				message = "is required"
+			}
+			errs = append(errs, fieldError{Field: e.Field(), Message: message})
 		}
+		return errs, true
+	case errors.As(err, &fieldErr):
+		return []fieldError{fieldErr}, true
+	case errors.As(err, &typeErr):
+		return []fieldError{{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}, true
+	default:
+		return nil, false
+	}
+}
 
-		httpRequestDuration.WithLabelValues(c.Request.Method, path).Observe(duration)
-		httpRequestsTotal.WithLabelValues(c.Request.Method, path, status).Inc()
+// jsonTypeName describes t the way a JSON client would think of it
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	default:
+		return "a " + t.String()
 	}
 }
 
-func main() {
-	// Initialize structured logger with file output
-	logger, err := newSlogLogger()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
-		os.Exit(1)
+// bindItem decodes and validates the JSON body into item. On failure it returns the status to
+// answer with, 422 for invalid fields, 413 for an oversized body or 400 for an unreadable one,
+// and the error body.
+func bindItem(c *gin.Context, item *Item) (int, apiError, error) {
+	err := c.ShouldBindJSON(item)
+	if err == nil {
+		err = item.validate()
 	}
+	if err == nil {
+		return 0, apiError{}, nil
+	}
+	status, body := bindError(err)
+	return status, body, err
+}
 
-	// Initialize tracer
-	tp, err := initTracer()
-	if err != nil {
-		logger.Error("Failed to initialize tracer", "error", err)
-	} else {
-		defer func() {
-			if err := tp.Shutdown(context.Background()); err != nil {
-				logger.Error("Error shutting down tracer provider", "error", err)
-			}
-		}()
-		logger.Info("Tracer initialized successfully")
+// bindError maps a failure to decode or validate a request body to its status and error body
+func bindError(err error) (int, apiError) {
+	if isBodyTooLarge(err) {
+		return http.StatusRequestEntityTooLarge, errBodyTooLarge
 	}
+	if errs, ok := fieldErrors(err); ok {
+		return http.StatusUnprocessableEntity, validationError(errs)
+	}
+	return http.StatusBadRequest, apiError{Code: "bad_request", Message: "Request body must be valid JSON"}
+}
 
-	// Create Gin router
-	router := gin.Default()
+// operationStatus maps a rejected request's HTTP status to its item_operations_total status label
+func operationStatus(status int) string {
+	if status == http.StatusUnprocessableEntity {
+		return "validation_error"
+	}
+	return "bad_request"
+}
 
-	// Add OpenTelemetry middleware
-	router.Use(otelgin.Middleware("the-app"))
+// Fake database
+var (
+	// seedItems populate an empty store on startup, taking IDs 1..n
+	seedItems = []Item{
+		{Name: "laptop", Price: 1200.0},
+		{Name: "mouse", Price: 25.0},
+		{Name: "keyboard", Price: 75.0},
+	}
 
-	// Add structured logging middleware
-	router.Use(structuredLogMiddleware(logger))
+	// store is replaced in main with the backend selected by STORAGE_BACKEND
+	store ItemStore = newMemoryItemStore()
 
-	// Add Prometheus middleware
-	router.Use(prometheusMiddleware())
+	// collectorConn is the OTLP collector connection, nil when tracing is disabled
+	collectorConn *grpc.ClientConn
 
-	// Prometheus metrics endpoint
-	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	// allItems is the search catalog; gin.H keeps it marshalable as both JSON and XML
+	allItems = []gin.H{
+		{"name": "laptop", "price": 1200.0, "is_offer": false},
+		{"name": "mouse", "price": 25.0, "is_offer": true},
+		{"name": "keyboard", "price": 75.0, "is_offer": false},
+		{"name": "monitor", "price": 300.0, "is_offer": true},
+		{"name": "webcam", "price": 50.0, "is_offer": false},
+	}
+)
 
-	// API endpoints
-	router.GET("/", readRoot)
-	router.GET("/items/:item_id", readItem)
-	router.GET("/search/", searchItems)
-	router.POST("/items/", createItem)
-	router.GET("/status", getStatus)
-	router.GET("/error-500", getError500)
-	router.GET("/error-400", getError400)
+// metricsNamespacePattern is what METRICS_NAMESPACE must match to form valid metric names
+var metricsNamespacePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
 
-	logger.Info("Starting server on port 5060...")
-	if err := router.Run(":5060"); err != nil {
-		logger.Error("Failed to start server", "error", err)
-		os.Exit(1)
-	}
-}
+// registerMetrics registers the runtime collectors and every application metric on reg. The
+// application metrics are prefixed with METRICS_NAMESPACE when it's set (theapp_http_requests_total);
+// the standard go_*, process_* and promhttp_* metrics keep their usual names.
+func registerMetrics(reg prometheus.Registerer) error {
+	// Register runtime and process collectors explicitly; the private registry starts empty
+	reg.MustRegister(collectors.NewGoCollector())
+	reg.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
 
-// Helper to get logger from context
-func getLogger(c *gin.Context) *slog.Logger {
-	logger, exists := c.Get("logger")
-	if !exists {
-		return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	namespace := getEnv("METRICS_NAMESPACE", "")
+	if namespace != "" {
+		if !metricsNamespacePattern.MatchString(namespace) {
+			return fmt.Errorf("invalid METRICS_NAMESPACE %q", namespace)
+		}
+		reg = prometheus.WrapRegistererWithPrefix(namespace+"_", reg)
 	}
-	return logger.(*slog.Logger)
-}
 
-// Handler functions
+	// Register Prometheus metrics
+	reg.MustRegister(buildInfo)
+	reg.MustRegister(httpRequestsTotal)
+	reg.MustRegister(httpResponsesByClass)
+	reg.MustRegister(httpRequestDuration)
+	reg.MustRegister(httpRequestSize)
+	reg.MustRegister(httpResponseSize)
+	reg.MustRegister(httpRequestsInFlight)
+	reg.MustRegister(httpRateLimitedTotal)
+	reg.MustRegister(httpPanicsTotal)
+	reg.MustRegister(httpRequestTimeoutsTotal)
+	reg.MustRegister(httpRequestBodyTooLargeTotal)
+	reg.MustRegister(itemOperationsTotal)
+	reg.MustRegister(searchRequestsTotal)
+	reg.MustRegister(searchResultsCount)
+	reg.MustRegister(searchEmptyResultsTotal)
+	reg.MustRegister(searchDuration)
+	reg.MustRegister(searchPageSize)
+	reg.MustRegister(appActiveWorkers)
+	reg.MustRegister(appErrorsTotal)
+	reg.MustRegister(logWriteErrorsTotal)
+	reg.MustRegister(traceExporterState)
+	reg.MustRegister(itemsTotal)
+	reg.MustRegister(itemsByPriceBucket)
 
-func readRoot(c *gin.Context) {
-	logger := getLogger(c)
-	logger.Info("Accessed root endpoint")
+	buildInfo.WithLabelValues(version, commit, runtime.Version()).Set(1)
+	return nil
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Welcome to the Go application!",
-	})
+func init() {
+	// Report validation errors by JSON field name rather than Go field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		})
+	}
 }
 
-func readItem(c *gin.Context) {
-	logger := getLogger(c)
+// getEnv returns the value of an environment variable or a fallback when unset
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
 
-	itemIDStr := c.Param("item_id")
-	itemID, err := strconv.Atoi(itemIDStr)
+// getEnvBool parses a boolean environment variable, returning fallback when unset or invalid
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(fallback)))
 	if err != nil {
-		logger.Warn("Invalid item ID", "item_id", itemIDStr, "error", err)
-		itemOperationsTotal.WithLabelValues("read", "bad_request").Inc()
-		c.JSON(http.StatusBadRequest, gin.H{"detail": "Invalid item ID"})
-		return
+		return fallback
 	}
+	return value
+}
 
-	item, exists := fakeItemsDB[itemID]
-	if !exists {
-		logger.Info("Item not found", "item_id", itemID)
-		itemOperationsTotal.WithLabelValues("read", "not_found").Inc()
-		c.JSON(http.StatusNotFound, gin.H{"detail": "Item not found"})
-		return
+// getEnvDuration parses a duration environment variable, returning fallback when unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(getEnv(key, fallback.String()))
+	if err != nil {
+		return fallback
 	}
+	return value
+}
 
-	response := map[string]interface{}{
-		"item_id": itemID,
-	}
-	for k, v := range item {
-		response[k] = v
+// getEnvInt parses an integer environment variable, returning fallback when unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(getEnv(key, strconv.Itoa(fallback)))
+	if err != nil {
+		return fallback
 	}
+	return value
+}
 
-	logger.Info("Successfully retrieved item", "item_id", itemID)
-	itemOperationsTotal.WithLabelValues("read", "success").Inc()
-	c.JSON(http.StatusOK, response)
+// getEnvFloat parses a float environment variable, returning fallback when unset or invalid
+func getEnvFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, strconv.FormatFloat(fallback, 'f', -1, 64)), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
 }
 
-func searchItems(c *gin.Context) {
-	logger := getLogger(c)
-	searchRequestsTotal.Inc()
+// getEnvList splits a comma-separated environment variable, dropping empty entries
+func getEnvList(key, fallback string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, fallback), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
 
-	name := c.Query("name")
-	minPriceStr := c.DefaultQuery("min_price", "0")
-	minPrice, err := strconv.ParseFloat(minPriceStr, 64)
+// isWideOpenProxy reports whether a TRUSTED_PROXIES entry covers the whole IPv4 or IPv6 range
+func isWideOpenProxy(proxy string) bool {
+	_, network, err := net.ParseCIDR(proxy)
 	if err != nil {
-		logger.Warn("Invalid min_price query param", "min_price", minPriceStr, "error", err)
-		minPrice = 0
+		return false
 	}
+	ones, _ := network.Mask.Size()
+	return ones == 0
+}
 
-	var results []map[string]interface{}
-	for _, item := range allItems {
-		itemName := item["name"].(string)
-		itemPrice := item["price"].(float64)
-
-		nameMatch := name == "" || strings.Contains(strings.ToLower(itemName), strings.ToLower(name))
-		priceMatch := itemPrice >= minPrice
+// parseBuckets parses comma-separated, strictly increasing histogram bucket boundaries,
+// returning fallback when raw is empty or invalid
+func parseBuckets(raw string, fallback []float64) ([]float64, error) {
+	if strings.TrimSpace(raw) == "" {
+		return fallback, nil
+	}
 
-		if nameMatch && priceMatch {
-			results = append(results, item)
+	var buckets []float64
+	for _, field := range strings.Split(raw, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return fallback, fmt.Errorf("invalid bucket %q: %w", field, err)
 		}
+		if len(buckets) > 0 && value <= buckets[len(buckets)-1] {
+			return fallback, fmt.Errorf("buckets must be strictly increasing, got %v after %v", value, buckets[len(buckets)-1])
+		}
+		buckets = append(buckets, value)
 	}
+	return buckets, nil
+}
 
-	searchResultsCount.Observe(float64(len(results)))
-	logger.Info("Search performed", "query_name", name, "min_price", minPrice, "results_found", len(results))
-
-	c.JSON(http.StatusOK, gin.H{
-		"search_results": results,
-	})
+// newPropagator builds the composite propagator listed in OTEL_PROPAGATORS. Extraction tries
+// every format, so upstreams sending either W3C or B3 headers join the same trace.
+func newPropagator() (propagation.TextMapPropagator, error) {
+	var propagators []propagation.TextMapPropagator
+	for _, name := range getEnvList("OTEL_PROPAGATORS", "tracecontext,baggage,b3") {
+		switch name {
+		case "tracecontext":
+			propagators = append(propagators, propagation.TraceContext{})
+		case "baggage":
+			propagators = append(propagators, propagation.Baggage{})
+		case "b3":
+			propagators = append(propagators, b3.New())
+		case "b3multi":
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case "none":
+		default:
+			return nil, fmt.Errorf("unknown propagator %q", name)
+		}
+	}
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
 }
 
-func createItem(c *gin.Context) {
-	logger := getLogger(c)
-	var item Item
-	if err := c.ShouldBindJSON(&item); err != nil {
-		logger.Warn("Failed to bind JSON for create item", "error", err.Error())
-		itemOperationsTotal.WithLabelValues("create", "bad_request").Inc()
-		c.JSON(http.StatusBadRequest, gin.H{"detail": err.Error()})
-		return
+// newSampler builds the sampler selected by OTEL_TRACES_SAMPLER, using OTEL_TRACES_SAMPLER_ARG
+// as the ratio for the traceidratio variants
+func newSampler() (sdktrace.Sampler, error) {
+	samplerName := getEnv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+
+	ratio := 1.0
+	if arg := os.Getenv("OTEL_TRACES_SAMPLER_ARG"); arg != "" {
+		parsed, err := strconv.ParseFloat(arg, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return nil, fmt.Errorf("OTEL_TRACES_SAMPLER_ARG must be a ratio between 0 and 1, got %q", arg)
+		}
+		ratio = parsed
 	}
 
-	logger.Info("Item created successfully", "item_name", item.Name, "item_price", item.Price)
-	itemOperationsTotal.WithLabelValues("create", "success").Inc()
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Item created successfully",
-		"item":    item,
-	})
+	switch samplerName {
+	case "always_on":
+		return sdktrace.AlwaysSample(), nil
+	case "always_off":
+		return sdktrace.NeverSample(), nil
+	case "traceidratio":
+		return sdktrace.TraceIDRatioBased(ratio), nil
+	case "parentbased_always_on":
+		return sdktrace.ParentBased(sdktrace.AlwaysSample()), nil
+	case "parentbased_always_off":
+		return sdktrace.ParentBased(sdktrace.NeverSample()), nil
+	case "parentbased_traceidratio":
+		return sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio)), nil
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_SAMPLER %q", samplerName)
+	}
 }
 
-func getStatus(c *gin.Context) {
-	getLogger(c).Info("Health check performed")
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"version": "1.0",
-	})
+// newResource describes this service for both traces and OTel metrics
+func newResource(ctx context.Context, serviceName string) (*resource.Resource, error) {
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version),
+			semconv.DeploymentEnvironment(getEnv("ENVIRONMENT", "development")),
+		),
+		resource.WithHost(),
+		resource.WithProcess(),
+	)
+	// A partial resource still carries the service attributes, so only fail on other errors
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+	return res, nil
 }
 
-func getError500(c *gin.Context) {
-	getLogger(c).Error("Simulating 500 Internal Server Error")
-	c.JSON(http.StatusInternalServerError, gin.H{
-		"detail": "Internal Server Error",
+// newOTLPTraceExporter builds the span exporter for OTEL_EXPORTER_OTLP_PROTOCOL (grpc or
+// http/protobuf). Both share OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_INSECURE; only
+// grpc dials up front and returns its connection, the HTTP exporter connects per export.
+func newOTLPTraceExporter(ctx context.Context) (sdktrace.SpanExporter, *grpc.ClientConn, error) {
+	protocol := strings.ToLower(getEnv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc"))
+	defaultEndpoint := "otel-collector:4317"
+	if protocol == "http/protobuf" {
+		defaultEndpoint = "otel-collector:4318"
+	}
+
+	// The spec allows a URL here, but both exporters expect host:port
+	endpoint := getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", defaultEndpoint)
+	endpoint = strings.TrimPrefix(strings.TrimPrefix(endpoint, "http://"), "https://")
+	useInsecure := getEnvBool("OTEL_EXPORTER_OTLP_INSECURE", true)
+
+	switch protocol {
+	case "grpc":
+		creds := insecure.NewCredentials()
+		if !useInsecure {
+			creds = credentials.NewClientTLSFromCert(nil, "")
+		}
+
+		// Bound the blocking dial so an unreachable collector can't hang startup
+		dialCtx, cancel := context.WithTimeout(ctx, getEnvDuration("OTEL_EXPORTER_OTLP_DIAL_TIMEOUT", 5*time.Second))
+		defer cancel()
+
+		conn, err := grpc.DialContext(
+			dialCtx,
+			endpoint,
+			grpc.WithTransportCredentials(creds),
+			grpc.WithBlock(),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create gRPC connection: %w", err)
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithGRPCConn(conn))
+		if err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		return exporter, conn, nil
+	case "http/protobuf":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+		if useInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP HTTP exporter: %w", err)
+		}
+		return exporter, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported OTEL_EXPORTER_OTLP_PROTOCOL %q", protocol)
+	}
+}
+
+// initTracer initializes OpenTelemetry tracer, returning the collector connection for readiness
+// checks; the connection is nil when traces go over OTLP HTTP
+func initTracer(serviceName string, logger *slog.Logger) (*sdktrace.TracerProvider, *grpc.ClientConn, error) {
+	ctx := context.Background()
+
+	sampler, err := newSampler()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// OTEL_TRACES_EXPORTER=console pretty-prints spans to stdout for local debugging without a collector
+	var exporter sdktrace.SpanExporter
+	var conn *grpc.ClientConn
+	switch kind := strings.ToLower(getEnv("OTEL_TRACES_EXPORTER", "otlp")); kind {
+	case "otlp":
+		exporter, conn, err = newOTLPTraceExporter(ctx)
+		if err == nil {
+			// Stop trying a collector that keeps failing; see exporterbreaker.go
+			exporter = newBreakerExporter(exporter,
+				getEnvInt("OTEL_EXPORTER_BREAKER_FAILURES", 3),
+				getEnvDuration("OTEL_EXPORTER_BREAKER_COOLDOWN", 30*time.Second),
+				logger,
+			)
+		}
+	case "console":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		err = fmt.Errorf("unsupported OTEL_TRACES_EXPORTER %q", kind)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := newResource(ctx, serviceName)
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+		return nil, nil, err
+	}
+
+	// Optionally export failing spans even when the sampler dropped them, see sampling.go
+	var spanProcessor sdktrace.SpanProcessor = sdktrace.NewBatchSpanProcessor(exporter)
+	if getEnvBool("OTEL_TRACES_SAMPLE_ERRORS", false) {
+		sampler = errorAwareSampler{base: sampler}
+		spanProcessor = errorSpanProcessor{SpanProcessor: spanProcessor}
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(spanProcessor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	)
+
+	otel.SetTracerProvider(tp)
+	return tp, conn, nil
+}
+
+// logLevel is shared by all loggers so the level can be changed at runtime
+var logLevel = new(slog.LevelVar)
+
+// logLevels are the accepted level names; slog's own parser also takes offsets like "info+2"
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// newLogFileWriter creates the log directory if needed and returns a size-rotated writer for the log file
+func newLogFileWriter(dir, name string) (io.Writer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	// lumberjack opens the file lazily, so probe it now to fall back to stdout at startup
+	path := filepath.Join(dir, name)
+	logFile, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	logFile.Close()
+
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    getEnvInt("LOG_MAX_SIZE_MB", 100),
+		MaxBackups: getEnvInt("LOG_MAX_BACKUPS", 5),
+		MaxAge:     getEnvInt("LOG_MAX_AGE_DAYS", 7),
+	}, nil
+}
+
+// newSlogLogger creates a new structured logger that writes to both stdout and file,
+// falling back to stdout only when the log file can't be opened or stops accepting writes
+func newSlogLogger() *slog.Logger {
+	// Parse LOG_LEVEL (debug, info, warn, error), defaulting to info
+	levelStr := getEnv("LOG_LEVEL", "info")
+	level, levelOK := logLevels[strings.ToLower(levelStr)]
+	if !levelOK {
+		level = slog.LevelInfo
+	}
+	logLevel.Set(level)
+
+	// Select JSON (default) or human-readable text output via LOG_FORMAT; LOG_SOURCE adds the
+	// file:line of each log call
+	opts := &slog.HandlerOptions{
+		Level:     logLevel,
+		AddSource: getEnvBool("LOG_SOURCE", false),
+	}
+	logFormat := getEnv("LOG_FORMAT", "json")
+	newHandler := func(w io.Writer) slog.Handler {
+		if logFormat == "text" {
+			return slog.NewTextHandler(w, opts)
+		}
+		return slog.NewJSONHandler(w, opts)
+	}
+
+	var out io.Writer = os.Stdout
+	logFile, fileErr := newLogFileWriter(getEnv("LOG_DIR", "/app/logs"), getEnv("LOG_FILE", "app.log"))
+	if fileErr == nil {
+		// Write to both stdout and file; the warning goes through its own stdout handler since
+		// it's raised from inside a write on the main one
+		stdoutLogger := slog.New(newHandler(os.Stdout))
+		out = io.MultiWriter(os.Stdout, &degradingWriter{
+			w: logFile,
+			onDisable: func(err error) {
+				stdoutLogger.Warn("Log file writes keep failing, file logging disabled", "error", err)
+			},
+		})
+	}
+
+	logger := slog.New(newHandler(out))
+	if fileErr != nil {
+		logger.Warn("File logging disabled, logging to stdout only", "error", fileErr)
+	}
+	if !levelOK {
+		logger.Warn("Invalid LOG_LEVEL, defaulting to info", "log_level", levelStr)
+	}
+	if logFormat != "json" && logFormat != "text" {
+		logger.Warn("Invalid LOG_FORMAT, defaulting to json", "log_format", logFormat)
+	}
+	return logger
+}
+
+// corsMiddleware sets CORS headers for allowed origins and answers preflight requests directly
+func corsMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Origin")
+		if !allowAll && !allowed[origin] {
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		c.Header("Access-Control-Expose-Headers", "Idempotent-Replayed, Location, X-Request-ID")
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Type, Idempotency-Key, X-API-Key, X-Request-ID")
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// apiKeyMiddleware requires a valid X-API-Key header, answering 401 when it's missing
+// and 403 when it doesn't match; with no keys configured every request is let through
+func apiKeyMiddleware(keys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(keys) == 0 {
+			c.Next()
+			return
+		}
+
+		logger := getLogger(c)
+		provided := c.GetHeader("X-API-Key")
+		if provided == "" {
+			logger.Warn("Rejected request without API key", "method", c.Request.Method)
+			respondError(c, http.StatusUnauthorized, "unauthorized", "Missing API key")
+			return
+		}
+
+		for _, key := range keys {
+			if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		// Never log the attempted key itself
+		logger.Warn("Rejected request with invalid API key", "method", c.Request.Method)
+		respondError(c, http.StatusForbidden, "unauthorized", "Invalid API key")
+	}
+}
+
+// bearerTokenMiddleware requires an "Authorization: Bearer <token>" header matching token
+func bearerTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			getLogger(c).Warn("Rejected request with missing or invalid bearer token")
+			c.Header("WWW-Authenticate", "Bearer")
+			respondError(c, http.StatusUnauthorized, "unauthorized", "Unauthorized")
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestIDMiddleware propagates the incoming X-Request-ID header or generates a new one
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+
+		c.Set("request_id", requestID)
+		c.Header("X-Request-ID", requestID)
+		c.Next()
+	}
+}
+
+// structuredLogMiddleware adds a structured logger (slog) to the context. The baggageKeys entries
+// of the incoming W3C baggage, which otelgin has already extracted into the request context for
+// handlers to read, are added to the logger under "baggage" and to the request span.
+func structuredLogMiddleware(logger *slog.Logger, baggageKeys []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// route is the matched template for grouping, path the concrete URL for detail
+		route := handlerLabel(c)
+		requestLogger := logger.With(
+			"route", route,
+			"path", c.Request.URL.Path,
+			"client_ip", c.ClientIP(),
+			"user_agent", c.Request.UserAgent(),
+		)
+
+		if requestID := c.GetString("request_id"); requestID != "" {
+			requestLogger = requestLogger.With("request_id", requestID)
+		}
+
+		span := trace.SpanFromContext(c.Request.Context())
+		if span.SpanContext().IsValid() {
+			requestLogger = requestLogger.With(
+				"trace_id", span.SpanContext().TraceID().String(),
+				"span_id", span.SpanContext().SpanID().String(),
+			)
+		}
+
+		bag := baggage.FromContext(c.Request.Context())
+		var baggageAttrs []any
+		for _, key := range baggageKeys {
+			if member := bag.Member(key); member.Key() != "" {
+				baggageAttrs = append(baggageAttrs, slog.String(key, member.Value()))
+				span.SetAttributes(attribute.String("baggage."+key, member.Value()))
+			}
+		}
+		if len(baggageAttrs) > 0 {
+			requestLogger = requestLogger.With(slog.Group("baggage", baggageAttrs...))
+		}
+
+		c.Set("logger", requestLogger)
+		c.Next()
+	}
+}
+
+// accessLogMiddleware emits one structured log line per request through the request logger,
+// which already carries route, path, request_id, trace_id and span_id. Requests slower than slowThreshold
+// are logged at warn so log-based alerts can pick them up.
+func accessLogMiddleware(slowThreshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		latency := time.Since(start)
+
+		bytesWritten := c.Writer.Size()
+		if bytesWritten < 0 {
+			bytesWritten = 0
+		}
+
+		level, msg := slog.LevelInfo, "HTTP request"
+		if latency > slowThreshold {
+			level, msg = slog.LevelWarn, "Slow HTTP request"
+		}
+		getLogger(c).Log(c.Request.Context(), level, msg,
+			"method", c.Request.Method,
+			"status", c.Writer.Status(),
+			"latency_ms", float64(latency.Microseconds())/1000,
+			"bytes", bytesWritten,
+		)
+	}
+}
+
+// recoveryMiddleware turns handler panics into JSON 500s, recording them in logs, traces and metrics
+func recoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				path := handlerLabel(c)
+
+				getLogger(c).Error("Recovered from panic", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+
+				span := trace.SpanFromContext(c.Request.Context())
+				span.RecordError(fmt.Errorf("panic: %v", r), trace.WithStackTrace(true))
+				span.SetStatus(codes.Error, "panic recovered")
+
+				httpPanicsTotal.WithLabelValues(c.Request.Method, path).Inc()
+				respondError(c, http.StatusInternalServerError, "panic", "Internal Server Error")
+			}
+		}()
+		c.Next()
+	}
+}
+
+// countingReadCloser counts the bytes read from a request body of unknown length
+type countingReadCloser struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// recordError counts an error response for the current route in app_errors_total and tags the
+// request span with error.category. Categories
+// are validation, bad_request, not_found, unauthorized, conflict, rate_limited, too_large,
+// not_acceptable, timeout, unavailable, downstream, internal and panic.
+func recordError(c *gin.Context, category string) {
+	handler := handlerLabel(c)
+	appErrorsTotal.WithLabelValues(handler, category).Inc()
+	trace.SpanFromContext(c.Request.Context()).SetAttributes(errorCategoryKey.String(category))
+}
+
+// resettableMetrics are the metric vectors POST /metrics/reset clears. Unlabelled counters and
+// histograms have no Reset and keep their values, and gauges describing current state (build
+// info, in-flight requests, active workers, stored items, exporter state) are left alone.
+var resettableMetrics = []interface{ Reset() }{
+	httpRequestsTotal,
+	httpResponsesByClass,
+	httpRequestDuration,
+	httpRequestSize,
+	httpResponseSize,
+	httpPanicsTotal,
+	httpRequestTimeoutsTotal,
+	httpRequestBodyTooLargeTotal,
+	itemOperationsTotal,
+	searchDuration,
+	appErrorsTotal,
+}
+
+// resetMetrics clears resettableMetrics; only routed when ALLOW_METRICS_RESET is set
+func resetMetrics(c *gin.Context) {
+	for _, metric := range resettableMetrics {
+		metric.Reset()
+	}
+	getLogger(c).Warn("Metrics reset", "metrics_reset", len(resettableMetrics))
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "Metrics reset",
+		"reset":   len(resettableMetrics),
+	})
+}
+
+// unknownHandler is the handler label for requests that matched no route
+const unknownHandler = "unknown"
+
+// handlerLabel returns the route template that matched the request, such as /items/:item_id,
+// or unknownHandler for 404s. Metrics must label requests with this and never with the raw
+// URL path: the templates are a fixed set, while raw paths (item IDs, scanners probing random
+// URLs) would create a new series per distinct path.
+func handlerLabel(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return unknownHandler
+}
+
+// trackWorker counts one goroutine of the given kind in app_active_workers; call the returned
+// function when the goroutine finishes
+func trackWorker(kind string) func() {
+	gauge := appActiveWorkers.WithLabelValues(kind)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// refreshPriceBuckets recounts stored items into items_by_price_bucket; handlers call it after
+// each successful write. A failed refresh is logged and leaves the previous counts in place.
+func refreshPriceBuckets(ctx context.Context, logger *slog.Logger) {
+	priceBucketsMu.Lock()
+	defer priceBucketsMu.Unlock()
+
+	items, err := store.List(ctx)
+	if err != nil {
+		logger.Warn("Failed to refresh price buckets", "error", err)
+		return
+	}
+	counts := make([]int, len(priceBuckets))
+	for _, item := range items {
+		for i, bucket := range priceBuckets {
+			if item.Price < bucket.upper {
+				counts[i]++
+				break
+			}
+		}
+	}
+	for i, bucket := range priceBuckets {
+		itemsByPriceBucket.WithLabelValues(bucket.label).Set(float64(counts[i]))
+	}
+}
+
+// observeWithTraceExemplar records value, attaching the trace ID as an exemplar when ctx carries a
+// sampled span so Grafana can link the observation to its trace in Tempo
+func observeWithTraceExemplar(ctx context.Context, observer prometheus.Observer, value float64) {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !ok || !spanCtx.IsValid() || !spanCtx.IsSampled() {
+		observer.Observe(value)
+		return
+	}
+	exemplarObserver.ObserveWithExemplar(value, prometheus.Labels{"trace_id": spanCtx.TraceID().String()})
+}
+
+// prometheusMiddleware records metrics for each request
+func prometheusMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		httpRequestsInFlight.Inc()
+		defer httpRequestsInFlight.Dec()
+
+		// Chunked bodies report ContentLength -1, so count what the handler reads instead
+		var bodyCounter *countingReadCloser
+		if c.Request.ContentLength < 0 && c.Request.Body != nil {
+			bodyCounter = &countingReadCloser{ReadCloser: c.Request.Body}
+			c.Request.Body = bodyCounter
+		}
+
+		start := time.Now()
+		c.Next()
+
+		duration := time.Since(start).Seconds()
+		status := strconv.Itoa(c.Writer.Status())
+		class := strconv.Itoa(c.Writer.Status()/100) + "xx"
+		path := handlerLabel(c)
+
+		// Size is -1 when nothing was written
+		responseSize := c.Writer.Size()
+		if responseSize < 0 {
+			responseSize = 0
+		}
+
+		requestSize := c.Request.ContentLength
+		if bodyCounter != nil {
+			requestSize = bodyCounter.n
+		}
+
+		observeWithTraceExemplar(c.Request.Context(), httpRequestDuration.WithLabelValues(c.Request.Method, path), duration)
+		httpRequestSize.WithLabelValues(c.Request.Method, path).Observe(float64(requestSize))
+		httpResponseSize.WithLabelValues(c.Request.Method, path).Observe(float64(responseSize))
+		httpRequestsTotal.WithLabelValues(c.Request.Method, path, status).Inc()
+		httpResponsesByClass.WithLabelValues(class).Inc()
+	}
+}
+
+func main() {
+	// Initialize structured logger with file output
+	logger := newSlogLogger()
+
+	// Register Prometheus metrics, labelling every series with the deployment environment so
+	// several environments can share one Prometheus
+	metricsRegisterer := prometheus.WrapRegistererWith(prometheus.Labels{
+		"environment": getEnv("ENVIRONMENT", "development"),
+	}, registry)
+	if err := registerMetrics(metricsRegisterer); err != nil {
+		logger.Error("Failed to register metrics", "error", err)
+		os.Exit(1)
+	}
+
+	if httpDurationBucketsErr != nil {
+		logger.Warn("Invalid HTTP_DURATION_BUCKETS, using default buckets", "error", httpDurationBucketsErr)
+	}
+
+	// Validate listen port
+	port := getEnv("PORT", getEnv("APP_PORT", "5060"))
+	if portNum, err := strconv.Atoi(port); err != nil || portNum < 1 || portNum > 65535 {
+		logger.Error("Invalid listen port", "port", port)
+		os.Exit(1)
+	}
+
+	// Initialize item storage
+	itemStore, err := newItemStore(context.Background())
+	if err != nil {
+		logger.Error("Failed to initialize item store", "error", err)
+		os.Exit(1)
+	}
+	store = itemStore
+	logger.Info("Item store initialized", "backend", getEnv("STORAGE_BACKEND", "memory"))
+	refreshPriceBuckets(context.Background(), logger)
+	idempotencyKeys = newIdempotencyStore(getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour))
+
+	// Show item prices formatted in DEFAULT_CURRENCY alongside the numeric price
+	if defaultCurrency, err = parseCurrency(os.Getenv("DEFAULT_CURRENCY")); err != nil {
+		logger.Warn("Invalid DEFAULT_CURRENCY, leaving prices unformatted", "error", err)
+	}
+
+	// Propagate trace context in the formats selected by OTEL_PROPAGATORS on incoming and outgoing requests
+	propagator, err := newPropagator()
+	if err != nil {
+		logger.Warn("Invalid OTEL_PROPAGATORS, using tracecontext,baggage", "error", err)
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	otel.SetTextMapPropagator(propagator)
+
+	// Initialize tracer; the same service name is used for the resource and the Gin middleware
+	serviceName := getEnv("OTEL_SERVICE_NAME", "the-app")
+	tp, conn, err := initTracer(serviceName, logger)
+	if err != nil {
+		logger.Warn("Failed to initialize tracer, continuing without tracing", "error", err)
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	} else {
+		collectorConn = conn
+		logger.Info("Tracer initialized successfully")
+	}
+
+	// Optionally mirror key HTTP metrics over OTLP, sharing the tracer's collector connection
+	var mp *sdkmetric.MeterProvider
+	var otelMetrics *otelHTTPMetrics
+	if getEnvBool("OTEL_METRICS_ENABLED", false) {
+		if collectorConn == nil {
+			logger.Warn("OTEL_METRICS_ENABLED is set but there is no gRPC collector connection, skipping OTLP metrics")
+		} else if mp, otelMetrics, err = initMeterProvider(context.Background(), collectorConn, serviceName); err != nil {
+			logger.Warn("Failed to initialize OTLP metrics", "error", err)
+		} else {
+			logger.Info("OTLP metrics export enabled")
+		}
+	}
+
+	// Register readiness checks; the collector is only checked when tracing connected at startup,
+	// since the app deliberately serves traffic without tracing otherwise
+	RegisterHealthCheck("database", store.Ping)
+	if collectorConn != nil {
+		RegisterHealthCheck("otel_collector", checkCollectorConn)
+	}
+
+	prettyJSON = getEnvBool("PRETTY_JSON", false)
+	if maxSearchQueryLength = getEnvInt("SEARCH_MAX_QUERY_LENGTH", 256); maxSearchQueryLength < 1 {
+		logger.Warn("Invalid SEARCH_MAX_QUERY_LENGTH, using 256", "search_max_query_length", maxSearchQueryLength)
+		maxSearchQueryLength = 256
+	}
+
+	// Distributed tracing demo; defaults to calling our own /status
+	downstreamURL = getEnv("DOWNSTREAM_URL", "http://localhost:"+port+"/status")
+
+	router, err := newRouter(logger, metricsRegisterer, serviceName, otelMetrics)
+	if err != nil {
+		logger.Error("Failed to set up router", "error", err)
+		os.Exit(1)
+	}
+
+	// Optionally smoke-test the wiring with in-process requests before taking traffic
+	if getEnvBool("RUN_SELFTEST", false) {
+		if err := runSelfTest(router, logger); err != nil {
+			logger.Error("Startup self-test failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Startup self-test passed")
+	}
+
+	// Bound how long a client may hold a connection so slow clients can't exhaust the server.
+	// WriteTimeout stays above REQUEST_TIMEOUT so timed-out handlers can still send their 503.
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadHeaderTimeout: getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       getEnvDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:      getEnvDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:       getEnvDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+	}
+
+	// Stop accepting traffic on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Starting server", "port", port)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Failed to start server", "error", err)
+			exitCode = 1
+		}
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received, draining connections...")
+	}
+	stop()
+
+	gracePeriod := getEnvDuration("SHUTDOWN_GRACE_PERIOD", 10*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+
+	// Report how long draining took in a log line rather than a gauge; nothing scrapes /metrics
+	// once the server has stopped
+	drainStart := time.Now()
+	err = srv.Shutdown(shutdownCtx)
+	logger.Info("Connection draining finished",
+		"shutdown_duration_seconds", time.Since(drainStart).Seconds(),
+		"grace_period", gracePeriod.String(),
+		"timed_out", errors.Is(err, context.DeadlineExceeded),
+	)
+	if err != nil {
+		logger.Error("Error shutting down server", "error", err)
+		exitCode = 1
+	}
+
+	// Flush the last metrics and batch of spans before exiting. Each flush gets its own timeout,
+	// so neither a drain that used up the grace period nor a slow metrics export cuts the
+	// spans short.
+	flushTimeout := getEnvDuration("TELEMETRY_FLUSH_TIMEOUT", 5*time.Second)
+	flush := func(name string, shutdown func(context.Context) error) {
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Error("Error shutting down "+name, "error", err)
+		}
+	}
+	if mp != nil {
+		flush("meter provider", mp.Shutdown)
+	}
+	if tp != nil {
+		flush("tracer provider", tp.Shutdown)
+	}
+
+	if err := store.Close(); err != nil {
+		logger.Error("Error closing item store", "error", err)
+	}
+
+	logger.Info("Server stopped")
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// newRouter builds the Gin engine, with the middleware and routes selected by the environment
+func newRouter(logger *slog.Logger, metricsRegisterer prometheus.Registerer, serviceName string, otelMetrics *otelHTTPMetrics) (*gin.Engine, error) {
+	// Create Gin router; access logging and panic recovery are our own middleware below
+	router := gin.New()
+
+	// Only honour X-Forwarded-For from TRUSTED_PROXIES; Gin trusts every proxy by default,
+	// which lets clients spoof ClientIP
+	proxies := getEnvList("TRUSTED_PROXIES", "127.0.0.1,::1")
+	if len(proxies) == 1 && strings.EqualFold(proxies[0], "none") {
+		proxies = nil
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	for _, proxy := range proxies {
+		if isWideOpenProxy(proxy) {
+			logger.Warn("TRUSTED_PROXIES trusts every address; clients can spoof their IP via X-Forwarded-For", "proxy", proxy)
+		}
+	}
+
+	// Add CORS middleware; it runs first so preflight requests are answered before
+	// tracing and metrics, keeping them out of the request counts and latency
+	router.Use(corsMiddleware(getEnvList("CORS_ALLOWED_ORIGINS", "*")))
+
+	// Add OpenTelemetry middleware
+	router.Use(otelgin.Middleware(serviceName))
+
+	// Add request ID middleware
+	router.Use(requestIDMiddleware())
+
+	// Add structured logging middleware, logging the LOG_BAGGAGE_KEYS baggage entries
+	baggageKeys := getEnvList("LOG_BAGGAGE_KEYS", "")
+	if len(baggageKeys) > 0 && !slices.Contains(otel.GetTextMapPropagator().Fields(), "baggage") {
+		logger.Warn("LOG_BAGGAGE_KEYS is set but OTEL_PROPAGATORS doesn't include baggage, so no baggage will be read")
+	}
+	router.Use(structuredLogMiddleware(logger, baggageKeys))
+
+	// Add access log middleware
+	router.Use(accessLogMiddleware(time.Duration(getEnvInt("SLOW_REQUEST_MS", 1000)) * time.Millisecond))
+
+	// Add Prometheus middleware
+	router.Use(prometheusMiddleware())
+
+	// Add OTel metrics middleware when OTLP metrics are enabled
+	if otelMetrics != nil {
+		router.Use(otelMetricsMiddleware(otelMetrics))
+	}
+
+	// Compress responses for clients that accept gzip. It runs inside the Prometheus middleware,
+	// so http_response_size_bytes records the compressed (on the wire) size, and outside recovery
+	// and the timeout: gzip closes the response with a 200 on its way out and never restores
+	// c.Writer, so error responses written after it would be lost. /metrics is left alone for
+	// scrapers, and pprof output is already compressed.
+	if getEnvBool("GZIP_ENABLED", false) {
+		router.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/metrics", "/debug/pprof/"})))
+	}
+
+	// Add panic recovery middleware; registered after Prometheus so recovered 500s are counted
+	router.Use(recoveryMiddleware())
+
+	// Add per-client rate limiting when RATE_LIMIT_RPS is set
+	if rps := getEnvFloat("RATE_LIMIT_RPS", 0); rps > 0 {
+		burst := getEnvInt("RATE_LIMIT_BURST", int(math.Ceil(rps)))
+		router.Use(rateLimitMiddleware(newIPRateLimiter(rate.Limit(rps), burst)))
+		logger.Info("Rate limiting enabled", "rps", rps, "burst", burst)
+	}
+
+	// Bound every request (except /metrics) by REQUEST_TIMEOUT
+	router.Use(timeoutMiddleware(getEnvDuration("REQUEST_TIMEOUT", 30*time.Second)))
+
+	// Cap request bodies at MAX_BODY_BYTES
+	if maxBodyBytes := getEnvInt("MAX_BODY_BYTES", 1<<20); maxBodyBytes > 0 {
+		router.Use(bodyLimitMiddleware(int64(maxBodyBytes)))
+	}
+
+	// Log request and response bodies when LOG_BODIES is set or the log level is debug; runs after
+	// gzip so bodies are logged uncompressed
+	router.Use(bodyLogMiddleware(
+		getEnvBool("LOG_BODIES", false),
+		getEnvInt("LOG_BODIES_MAX_BYTES", 4096),
+		getEnvList("LOG_REDACT_FIELDS", "password,token,secret,api_key,authorization"),
+	))
+
+	// Prometheus metrics endpoint, optionally protected by a bearer token; OpenMetrics is
+	// negotiated so exemplars reach Prometheus
+	metricsHandler := promhttp.InstrumentMetricHandler(metricsRegisterer,
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	metricsHandlers := []gin.HandlerFunc{gin.WrapH(metricsHandler)}
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		metricsHandlers = append([]gin.HandlerFunc{bearerTokenMiddleware(token)}, metricsHandlers...)
+	}
+	router.GET("/metrics", metricsHandlers...)
+
+	// Mutating endpoints require an API key when API_KEYS is set
+	apiKeys := getEnvList("API_KEYS", "")
+	if len(apiKeys) == 0 {
+		logger.Warn("API_KEYS not set, write endpoints are unauthenticated")
+	}
+	writes := router.Group("", apiKeyMiddleware(apiKeys))
+
+	// API endpoints
+	router.GET("/", readRoot)
+	router.GET("/items/", listItems)
+	router.GET("/items/random", readRandomItem)
+	router.GET("/items/:item_id", readItem)
+	router.GET("/search/", searchItems)
+	router.GET("/search/id-range", searchItemsByIDRange)
+	writes.POST("/items/", createItem)
+	writes.POST("/items/batch", createItemsBatch)
+	writes.PUT("/items/:item_id", updateItem)
+	writes.PATCH("/items/:item_id", patchItem)
+	writes.DELETE("/items/:item_id", deleteItem)
+	writes.DELETE("/items/", deleteAllItems)
+	router.GET("/status", getStatus)
+	router.GET("/version", getVersion)
+	router.GET("/healthz", getLiveness)
+	router.GET("/readyz", getReadiness)
+	router.GET("/error-500", getError500)
+	router.GET("/error-400", getError400)
+	router.GET("/slow", getSlow)
+	router.GET("/flaky", getFlaky)
+	router.GET("/burn", getBurn)
+
+	// Distributed tracing demo, calling DOWNSTREAM_URL
+	router.GET("/downstream", callDownstream)
+
+	// Test environments only: lets integration tests start from zeroed counters
+	if getEnvBool("ALLOW_METRICS_RESET", false) {
+		writes.POST("/metrics/reset", resetMetrics)
+		logger.Warn("POST /metrics/reset enabled; never set ALLOW_METRICS_RESET in production")
+	}
+
+	// API description, plus Swagger UI when ENABLE_DOCS is set
+	router.GET("/openapi.json", getOpenAPI)
+	if getEnvBool("ENABLE_DOCS", false) {
+		router.GET("/docs/*any", docsHandler())
+	}
+
+	// Admin endpoints
+	router.GET("/admin/loglevel", getLogLevel)
+	writes.PUT("/admin/loglevel", setLogLevel)
+
+	// Runtime profiling; off by default since profiles expose internals and cost CPU
+	if getEnvBool("ENABLE_PPROF", false) {
+		profiling := router.Group("/debug/pprof")
+		profiling.GET("/", gin.WrapF(pprof.Index))
+		profiling.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		profiling.GET("/profile", gin.WrapF(pprof.Profile))
+		profiling.GET("/symbol", gin.WrapF(pprof.Symbol))
+		profiling.POST("/symbol", gin.WrapF(pprof.Symbol))
+		profiling.GET("/trace", gin.WrapF(pprof.Trace))
+		// Index serves the named profiles (heap, goroutine, allocs, ...)
+		profiling.GET("/:profile", gin.WrapF(pprof.Index))
+		logger.Warn("pprof endpoints enabled under /debug/pprof")
+	}
+
+	// Unmatched paths get the JSON error envelope rather than Gin's plain-text 404
+	router.NoRoute(func(c *gin.Context) {
+		respondError(c, http.StatusNotFound, "not_found", "Not found")
+	})
+
+	return router, nil
+}
+
+// Helper to get logger from context
+func getLogger(c *gin.Context) *slog.Logger {
+	logger, exists := c.Get("logger")
+	if !exists {
+		return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	}
+	return logger.(*slog.Logger)
+}
+
+// searchSorters maps the search sort query param to a less function over catalog items
+var searchSorters = map[string]func(a, b gin.H) bool{
+	"price_asc": func(a, b gin.H) bool {
+		return a["price"].(float64) < b["price"].(float64)
+	},
+	"price_desc": func(a, b gin.H) bool {
+		return a["price"].(float64) > b["price"].(float64)
+	},
+	"name_asc": func(a, b gin.H) bool {
+		return strings.ToLower(a["name"].(string)) < strings.ToLower(b["name"].(string))
+	},
+	"name_desc": func(a, b gin.H) bool {
+		return strings.ToLower(a["name"].(string)) > strings.ToLower(b["name"].(string))
+	},
+}
+
+// searchFields is the allowlist for the search field/value params: each entry parses a value and
+// returns a predicate matching catalog items whose field equals it. Adding a searchable field
+// only takes a new entry here.
+var searchFields = map[string]func(value string) (func(item gin.H) bool, error){
+	"name": func(value string) (func(item gin.H) bool, error) {
+		return func(item gin.H) bool {
+			return strings.EqualFold(item["name"].(string), value)
+		}, nil
+	},
+	"price": func(value string) (func(item gin.H) bool, error) {
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("value must be a number for field price")
+		}
+		return func(item gin.H) bool {
+			return item["price"].(float64) == price
+		}, nil
+	},
+	"is_offer": func(value string) (func(item gin.H) bool, error) {
+		isOffer, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("value must be true or false for field is_offer")
+		}
+		return func(item gin.H) bool {
+			return item["is_offer"].(bool) == isOffer
+		}, nil
+	},
+}
+
+// parseSearchField reads the field and value query params, which come as a pair, returning
+// the predicate for them or nil when neither is set
+func parseSearchField(c *gin.Context) (func(item gin.H) bool, error) {
+	field, value := c.Query("field"), c.Query("value")
+	if field == "" && value == "" {
+		return nil, nil
+	}
+	if field == "" || value == "" {
+		return nil, fmt.Errorf("field and value must be given together")
+	}
+	newMatcher, ok := searchFields[field]
+	if !ok {
+		names := make([]string, 0, len(searchFields))
+		for name := range searchFields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("field must be one of %s", strings.Join(names, ", "))
+	}
+	return newMatcher(value)
+}
+
+// parsePagination reads the limit and offset query params, clamping limit to maxPageLimit
+func parsePagination(c *gin.Context) (limit, offset int, err error) {
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit)))
+	if err != nil || limit < 0 {
+		return 0, 0, fmt.Errorf("limit must be a non-negative integer")
+	}
+	offset, err = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+	return limit, offset, nil
+}
+
+// parseCursor reads the pagination mode; with pagination=cursor it returns the ID decoded from
+// the opaque cursor param, or 0 to start from the beginning. Cursor pages need a limit of at
+// least 1, since an empty page has no last entry to move the cursor past.
+func parseCursor(c *gin.Context, limit int) (cursorMode bool, afterID int, err error) {
+	switch c.DefaultQuery("pagination", "offset") {
+	case "offset":
+		return false, 0, nil
+	case "cursor":
+	default:
+		return false, 0, fmt.Errorf("pagination must be one of offset, cursor")
+	}
+	if limit < 1 {
+		return false, 0, fmt.Errorf("limit must be at least 1 with cursor pagination")
+	}
+
+	cursor := c.Query("cursor")
+	if cursor == "" {
+		return true, 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil {
+		afterID, err = strconv.Atoi(string(decoded))
+	}
+	if err != nil || afterID < 0 {
+		return false, 0, fmt.Errorf("cursor is invalid")
+	}
+	return true, afterID, nil
+}
+
+// encodeCursor hides the last ID of a page behind an opaque cursor
+func encodeCursor(lastID int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(lastID)))
+}
+
+// cursorWindow returns the bounds of the page of up to limit entries after afterID in ids, which
+// must be ascending, and the cursor for the following page, nil on the last page. Unlike offsets,
+// the cursor stays correct when earlier entries are added or removed between requests.
+func cursorWindow(ids []int, afterID, limit int) (start, end int, next any) {
+	start = sort.SearchInts(ids, afterID+1)
+	end = min(start+limit, len(ids))
+	// An empty page would hand back the cursor it was given, so it never links onward
+	if end < len(ids) && end > start {
+		next = encodeCursor(ids[end-1])
+	}
+	return start, end, next
+}
+
+// paginate returns the window of items selected by limit and offset
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+// prettyJSON indents JSON responses for human debugging; set from PRETTY_JSON
+var prettyJSON bool
+
+// respondJSON writes obj as the JSON response, indented when PRETTY_JSON is set. Handlers and
+// middleware write JSON only through this and respondError so the setting applies everywhere.
+// Success bodies keep their resource-specific shape; only errors are wrapped in an envelope.
+func respondJSON(c *gin.Context, status int, obj any) {
+	if prettyJSON {
+		c.IndentedJSON(status, obj)
+		return
+	}
+	c.JSON(status, obj)
+}
+
+// apiError is the body of every error response, wrapped as {"error": {...}}. Code is one of the
+// recordError categories, and TraceID lets clients quote the request to support.
+type apiError struct {
+	Code    string       `json:"code" example:"not_found"`
+	Message string       `json:"message" example:"Item not found"`
+	Details []fieldError `json:"details,omitempty"`
+	TraceID string       `json:"trace_id,omitempty" example:"4bf92f3577b34da6a3ce929d0e0e4736"`
+} // @name Error
+
+// validationError reports invalid request fields as a 422 body
+func validationError(errs []fieldError) apiError {
+	return apiError{Code: "validation", Message: "Request validation failed", Details: errs}
+}
+
+// respondError stops the handler chain and answers with the error envelope, counting the error
+// under code in app_errors_total
+func respondError(c *gin.Context, status int, code, message string) {
+	respondAPIError(c, status, apiError{Code: code, Message: message})
+}
+
+// respondAPIError is respondError for a prepared body, filling in the request's trace_id when a
+// span is active
+func respondAPIError(c *gin.Context, status int, body apiError) {
+	recordError(c, body.Code)
+	if spanCtx := trace.SpanContextFromContext(c.Request.Context()); spanCtx.IsValid() {
+		body.TraceID = spanCtx.TraceID().String()
+	}
+	c.Abort()
+	respondJSON(c, status, gin.H{"error": body})
+}
+
+// respondNegotiated writes data as JSON (the default) or XML according to the Accept header,
+// answering 406 when neither is acceptable
+func respondNegotiated(c *gin.Context, status int, data any) {
+	switch c.NegotiateFormat(binding.MIMEJSON, binding.MIMEXML, binding.MIMEXML2) {
+	case binding.MIMEJSON:
+		respondJSON(c, status, data)
+	case binding.MIMEXML, binding.MIMEXML2:
+		c.XML(status, data)
+	default:
+		getLogger(c).Warn("No acceptable response format", "accept", c.GetHeader("Accept"))
+		respondError(c, http.StatusNotAcceptable, "not_acceptable", "Supported formats are application/json and application/xml")
+	}
+}
+
+// itemETag derives a validator from the item's fields, so it changes whenever the item is
+// updated. It's weak because the same item can be served as either JSON or XML.
+func itemETag(item Item) string {
+	// Item holds only plain fields, so marshaling can't fail
+	data, _ := json.Marshal(item)
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header matches etag, using the weak
+// comparison RFC 9110 prescribes for that header
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler functions
+
+func readRoot(c *gin.Context) {
+	logger := getLogger(c)
+	logger.Info("Accessed root endpoint")
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "Welcome to the Go application!",
+	})
+}
+
+// @Summary  Get an item
+// @Tags     items
+// @Produce  json,xml
+// @Param    item_id       path     int     true   "Item ID"
+// @Param    If-None-Match header   string  false  "ETag from an earlier response"
+// @Success  200           {object} pricedItem
+// @Success  304           "Item unchanged since the given ETag"
+// @Failure  400           {object} errorResponse
+// @Failure  404           {object} errorResponse
+// @Failure  406           {object} errorResponse
+// @Failure  500           {object} errorResponse
+// @Router   /items/{item_id} [get]
+func readItem(c *gin.Context) {
+	logger := getLogger(c)
+
+	itemIDStr := c.Param("item_id")
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		logger.Warn("Invalid item ID", "item_id", itemIDStr, "error", err)
+		itemOperationsTotal.WithLabelValues("read", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", "Invalid item ID")
+		return
+	}
+
+	item, err := store.Get(c.Request.Context(), itemID)
+	if errors.Is(err, ErrItemNotFound) {
+		logger.Info("Item not found", "item_id", itemID)
+		itemOperationsTotal.WithLabelValues("read", "not_found").Inc()
+		respondError(c, http.StatusNotFound, "not_found", "Item not found")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read item", "item_id", itemID, "error", err)
+		itemOperationsTotal.WithLabelValues("read", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	// Let polling clients skip the body when the item hasn't changed since they last fetched it
+	etag := itemETag(item)
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		logger.Info("Item not modified", "item_id", itemID)
+		itemOperationsTotal.WithLabelValues("read", "not_modified").Inc()
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	logger.Info("Successfully retrieved item", "item_id", itemID)
+	itemOperationsTotal.WithLabelValues("read", "success").Inc()
+	respondNegotiated(c, http.StatusOK, withPrice(item))
+}
+
+// @Summary  Get a random item
+// @Tags     items
+// @Produce  json,xml
+// @Success  200 {object} pricedItem
+// @Failure  404 {object} errorResponse
+// @Failure  406 {object} errorResponse
+// @Failure  500 {object} errorResponse
+// @Router   /items/random [get]
+func readRandomItem(c *gin.Context) {
+	logger := getLogger(c)
+
+	items, err := store.List(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list items", "error", err)
+		itemOperationsTotal.WithLabelValues("read_random", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+	if len(items) == 0 {
+		logger.Info("No items to pick from")
+		itemOperationsTotal.WithLabelValues("read_random", "not_found").Inc()
+		respondError(c, http.StatusNotFound, "not_found", "No items available")
+		return
+	}
+
+	item := items[rand.Intn(len(items))]
+	logger.Info("Retrieved random item", "item_id", item.ID)
+	itemOperationsTotal.WithLabelValues("read_random", "success").Inc()
+	respondNegotiated(c, http.StatusOK, withPrice(item))
+}
+
+// @Summary  List items
+// @Tags     items
+// @Produce  json
+// @Param    limit      query    int     false  "Page size, at most 100; at least 1 with cursor pagination"  default(20)
+// @Param    offset     query    int     false  "Items to skip (offset pagination)"  default(0)
+// @Param    pagination query    string  false  "Pagination mode"  Enums(offset, cursor)  default(offset)
+// @Param    cursor     query    string  false  "next_cursor from the previous page (cursor pagination)"
+// @Success  200        {object} itemListResponse
+// @Failure  400        {object} errorResponse
+// @Failure  500        {object} errorResponse
+// @Router   /items/ [get]
+func listItems(c *gin.Context) {
+	logger := getLogger(c)
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		logger.Warn("Invalid pagination query params", "limit", c.Query("limit"), "offset", c.Query("offset"), "error", err)
+		itemOperationsTotal.WithLabelValues("list", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	cursorMode, afterID, err := parseCursor(c, limit)
+	if err != nil {
+		logger.Warn("Invalid cursor pagination query params", "pagination", c.Query("pagination"), "cursor", c.Query("cursor"), "error", err)
+		itemOperationsTotal.WithLabelValues("list", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	items, err := store.List(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list items", "error", err)
+		itemOperationsTotal.WithLabelValues("list", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	// The store lists in ID order, so pages are stable across requests
+	if cursorMode {
+		ids := make([]int, len(items))
+		for i, item := range items {
+			ids[i] = item.ID
+		}
+		start, end, next := cursorWindow(ids, afterID, limit)
+
+		logger.Info("Listed items", "total", len(items), "returned", end-start, "limit", limit, "after_id", afterID)
+		itemOperationsTotal.WithLabelValues("list", "success").Inc()
+		respondJSON(c, http.StatusOK, gin.H{
+			"items":       items[start:end],
+			"total":       len(items),
+			"limit":       limit,
+			"next_cursor": next,
+		})
+		return
+	}
+	page := paginate(items, limit, offset)
+
+	logger.Info("Listed items", "total", len(items), "returned", len(page), "limit", limit, "offset", offset)
+	itemOperationsTotal.WithLabelValues("list", "success").Inc()
+	respondJSON(c, http.StatusOK, gin.H{
+		"items":  page,
+		"total":  len(items),
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// searchResult is a catalog entry as returned by search
+type searchResult struct {
+	Name    string  `json:"name" xml:"name" example:"mouse"`
+	Price   float64 `json:"price" xml:"price" example:"25"`
+	IsOffer bool    `json:"is_offer" xml:"is_offer" example:"true"`
+	// Only present when DEFAULT_CURRENCY is set
+	Currency       string `json:"currency,omitempty" xml:"currency,omitempty" example:"USD"`
+	PriceFormatted string `json:"price_formatted,omitempty" xml:"price_formatted,omitempty" example:"$25.00"`
+} // @name SearchResult
+
+// searchResponse is a page of search results. It's a struct rather than gin.H because gin.H
+// marshals to XML as anonymous <map> elements in random order.
+type searchResponse struct {
+	XMLName       xml.Name       `json:"-" xml:"search"`
+	SearchResults []searchResult `json:"search_results" xml:"search_results>result"`
+	Total         int            `json:"total" xml:"total" example:"5"`
+	Limit         int            `json:"limit" xml:"limit" example:"20"`
+	*offsetPage
+	*cursorPage
+	Match string `json:"match" xml:"match" example:"contains"`
+} // @name SearchResponse
+
+// offsetPage is the position of an offset-paginated page
+type offsetPage struct {
+	Offset int `json:"offset" xml:"offset" example:"0"`
+}
+
+// cursorPage links a cursor-paginated page to the next one; NextCursor is nil on the last page
+type cursorPage struct {
+	NextCursor *string `json:"next_cursor" xml:"next_cursor,omitempty" example:"Mg"`
+}
+
+// newSearchResults converts catalog entries to search results, adding the defaultCurrency
+// price fields when one is configured
+func newSearchResults(entries []gin.H) []searchResult {
+	results := make([]searchResult, len(entries))
+	for i, entry := range entries {
+		result := searchResult{
+			Name:    entry["name"].(string),
+			Price:   entry["price"].(float64),
+			IsOffer: entry["is_offer"].(bool),
+		}
+		if defaultCurrency != "" {
+			result.Currency = defaultCurrency
+			result.PriceFormatted = formatPrice(result.Price, defaultCurrency)
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// @Summary  Search the catalog
+// @Tags     search
+// @Produce  json,xml
+// @Param    name       query    string  false  "Name to match, trimmed of surrounding whitespace; at most SEARCH_MAX_QUERY_LENGTH (256) characters"
+// @Param    match      query    string  false  "How name is matched"  Enums(contains, exact)  default(contains)
+// @Param    min_price  query    number  false  "Lowest price"  default(0)
+// @Param    max_price  query    number  false  "Highest price"
+// @Param    is_offer   query    bool    false  "Only offers (true) or only regular items (false)"
+// @Param    field      query    string  false  "Field to match exactly against value"  Enums(name, price, is_offer)
+// @Param    value      query    string  false  "Value field must equal; required with field"
+// @Param    sort       query    string  false  "Result order"  Enums(price_asc, price_desc, name_asc, name_desc)
+// @Param    limit      query    int     false  "Page size, at most 100; at least 1 with cursor pagination"  default(20)
+// @Param    offset     query    int     false  "Results to skip (offset pagination)"  default(0)
+// @Param    pagination query    string  false  "Pagination mode; cursor can't be combined with sort"  Enums(offset, cursor)  default(offset)
+// @Param    cursor     query    string  false  "next_cursor from the previous page (cursor pagination)"
+// @Success  200        {object} searchResponse
+// @Failure  400        {object} errorResponse
+// @Failure  406        {object} errorResponse
+// @Router   /search/ [get]
+func searchItems(c *gin.Context) {
+	logger := getLogger(c)
+	searchRequestsTotal.Inc()
+
+	// Surrounding whitespace is never part of an item name, so it shouldn't defeat an exact match
+	name := strings.TrimSpace(c.Query("name"))
+	if length := utf8.RuneCountInString(name); length > maxSearchQueryLength {
+		logger.Warn("Search name query too long", "name_length", length, "max_length", maxSearchQueryLength)
+		respondError(c, http.StatusBadRequest, "bad_request", fmt.Sprintf("name may be at most %d characters", maxSearchQueryLength))
+		return
+	}
+	minPriceStr := c.DefaultQuery("min_price", "0")
+	minPrice, err := strconv.ParseFloat(minPriceStr, 64)
+	if err != nil {
+		logger.Warn("Invalid min_price query param", "min_price", minPriceStr, "error", err)
+		minPrice = 0
+	}
+
+	// Upper bound is optional; nil means unbounded
+	var maxPrice *float64
+	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
+		parsed, err := strconv.ParseFloat(maxPriceStr, 64)
+		if err != nil {
+			logger.Warn("Invalid max_price query param", "max_price", maxPriceStr, "error", err)
+		} else if parsed < minPrice {
+			logger.Warn("max_price below min_price", "min_price", minPrice, "max_price", parsed)
+			respondError(c, http.StatusBadRequest, "bad_request", "max_price must be greater than or equal to min_price")
+			return
+		} else {
+			maxPrice = &parsed
+		}
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		logger.Warn("Invalid pagination query params", "limit", c.Query("limit"), "offset", c.Query("offset"), "error", err)
+		respondError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	searchPageSize.Observe(float64(limit))
+
+	matchMode := c.DefaultQuery("match", "contains")
+	if matchMode != "contains" && matchMode != "exact" {
+		logger.Warn("Invalid match query param", "match", matchMode)
+		respondError(c, http.StatusBadRequest, "bad_request", "match must be one of contains, exact")
+		return
+	}
+
+	// Offer filter is optional; nil means offers and regular items alike
+	var isOffer *bool
+	if isOfferStr := c.Query("is_offer"); isOfferStr != "" {
+		parsed, err := strconv.ParseBool(isOfferStr)
+		if err != nil {
+			logger.Warn("Invalid is_offer query param", "is_offer", isOfferStr)
+			respondError(c, http.StatusBadRequest, "bad_request", "is_offer must be true or false")
+			return
+		}
+		isOffer = &parsed
+	}
+
+	sortKey := c.Query("sort")
+	less, validSort := searchSorters[sortKey]
+	if sortKey != "" && !validSort {
+		logger.Warn("Invalid sort query param", "sort", sortKey)
+		respondError(c, http.StatusBadRequest, "bad_request", "sort must be one of price_asc, price_desc, name_asc, name_desc")
+		return
+	}
+
+	fieldMatch, err := parseSearchField(c)
+	if err != nil {
+		logger.Warn("Invalid field search query params", "field", c.Query("field"), "value", c.Query("value"), "error", err)
+		respondError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	// Search cursors walk catalog positions, which only works in catalog order
+	cursorMode, afterPosition, err := parseCursor(c, limit)
+	if err == nil && cursorMode && sortKey != "" {
+		err = fmt.Errorf("sort is not supported with cursor pagination")
+	}
+	if err != nil {
+		logger.Warn("Invalid cursor pagination query params", "pagination", c.Query("pagination"), "cursor", c.Query("cursor"), "error", err)
+		respondError(c, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	_, filterSpan := tracer.Start(c.Request.Context(), "search.filter", trace.WithAttributes(
+		attribute.String("search.query_name", name),
+		attribute.Float64("search.min_price", minPrice),
+	))
+
+	filterStart := time.Now()
+	var results []gin.H
+	var positions []int
+	for i, item := range allItems {
+		itemName := item["name"].(string)
+		itemPrice := item["price"].(float64)
+
+		nameMatch := true
+		if name != "" {
+			switch matchMode {
+			case "exact":
+				nameMatch = strings.ToLower(itemName) == strings.ToLower(name)
+			default:
+				nameMatch = strings.Contains(strings.ToLower(itemName), strings.ToLower(name))
+			}
+		}
+		priceMatch := itemPrice >= minPrice && (maxPrice == nil || itemPrice <= *maxPrice)
+		offerMatch := isOffer == nil || item["is_offer"].(bool) == *isOffer
+		fieldValueMatch := fieldMatch == nil || fieldMatch(item)
+
+		if nameMatch && priceMatch && offerMatch && fieldValueMatch {
+			results = append(results, item)
+			// Positions start at 1 so a cursor of 0 means the beginning
+			positions = append(positions, i+1)
+		}
+	}
+
+	// Stable so ties keep catalog insertion order
+	if less != nil {
+		sort.SliceStable(results, func(i, j int) bool {
+			return less(results[i], results[j])
+		})
+	}
+
+	filterSpan.SetAttributes(attribute.Int("search.results_found", len(results)))
+	filterSpan.End()
+	searchDuration.WithLabelValues(strconv.FormatBool(len(results) > 0)).Observe(time.Since(filterStart).Seconds())
+
+	searchResultsCount.Observe(float64(len(results)))
+	if len(results) == 0 {
+		searchEmptyResultsTotal.Inc()
+	}
+	logger.Info("Search performed", "query_name", name, "min_price", minPrice, "max_price", maxPrice,
+		"results_found", len(results),
+		"limit", limit, "offset", offset, "sort", sortKey, "match", matchMode, "is_offer", isOffer,
+		"field", c.Query("field"), "value", c.Query("value"))
+
+	response := searchResponse{Total: len(results), Limit: limit, Match: matchMode}
+	if cursorMode {
+		start, end, next := cursorWindow(positions, afterPosition, limit)
+		response.SearchResults = newSearchResults(results[start:end])
+		response.cursorPage = &cursorPage{}
+		if next, ok := next.(string); ok {
+			response.NextCursor = &next
+		}
+	} else {
+		response.SearchResults = newSearchResults(paginate(results, limit, offset))
+		response.offsetPage = &offsetPage{Offset: offset}
+	}
+	respondNegotiated(c, http.StatusOK, response)
+}
+
+// @Summary  List items with IDs in a range
+// @Tags     search
+// @Produce  json
+// @Param    from_id query    int  true  "First ID"
+// @Param    to_id   query    int  true  "Last ID, at most 1000 after from_id"
+// @Success  200     {object} idRangeResponse
+// @Failure  400     {object} errorResponse
+// @Failure  500     {object} errorResponse
+// @Router   /search/id-range [get]
+func searchItemsByIDRange(c *gin.Context) {
+	logger := getLogger(c)
+
+	fromID, fromErr := strconv.Atoi(c.Query("from_id"))
+	toID, toErr := strconv.Atoi(c.Query("to_id"))
+	if fromErr != nil || toErr != nil {
+		logger.Warn("Invalid ID range query params", "from_id", c.Query("from_id"), "to_id", c.Query("to_id"))
+		itemOperationsTotal.WithLabelValues("read_range", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", "from_id and to_id must be integers")
+		return
+	}
+	if fromID > toID {
+		logger.Warn("ID range is inverted", "from_id", fromID, "to_id", toID)
+		itemOperationsTotal.WithLabelValues("read_range", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", "from_id must be less than or equal to to_id")
+		return
+	}
+	// Unsigned so the width of a range spanning most of the int range can't overflow to negative
+	if uint64(toID-fromID) >= maxIDRangeWidth {
+		logger.Warn("ID range too wide", "from_id", fromID, "to_id", toID)
+		itemOperationsTotal.WithLabelValues("read_range", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", fmt.Sprintf("ID range may span at most %d IDs", maxIDRangeWidth))
+		return
+	}
+
+	items, err := store.List(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to list items", "error", err)
+		itemOperationsTotal.WithLabelValues("read_range", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	results := []Item{}
+	for _, item := range items {
+		if item.ID >= fromID && item.ID <= toID {
+			results = append(results, item)
+		}
+	}
+
+	logger.Info("ID range search performed", "from_id", fromID, "to_id", toID, "results_found", len(results))
+	itemOperationsTotal.WithLabelValues("read_range", "success").Inc()
+	respondJSON(c, http.StatusOK, gin.H{
+		"items": results,
+		"total": len(results),
+	})
+}
+
+// @Summary  Create an item
+// @Tags     items
+// @Accept   json
+// @Produce  json
+// @Param    item            body     Item    true   "Item to create; item_id is ignored"
+// @Param    Idempotency-Key header   string  false  "Replays the original response when a create is retried"
+// @Success  201             {object} itemResponse
+// @Failure  400             {object} errorResponse
+// @Failure  409             {object} errorResponse
+// @Failure  413             {object} errorResponse
+// @Failure  422             {object} errorResponse
+// @Failure  500             {object} errorResponse
+// @Security ApiKeyAuth
+// @Router   /items/ [post]
+func createItem(c *gin.Context) {
+	logger := getLogger(c)
+	var item Item
+	if status, body, err := bindItem(c, &item); err != nil {
+		logger.Warn("Rejected invalid item on create", "status", status, "error", err.Error())
+		itemOperationsTotal.WithLabelValues("create", operationStatus(status)).Inc()
+		respondAPIError(c, status, body)
+		return
+	}
+
+	// A retried Idempotency-Key gets the original response instead of a second item
+	idempotencyKey := c.GetHeader("Idempotency-Key")
+	var keyID idempotencyID
+	if idempotencyKey != "" {
+		keyID = newIdempotencyID(c, idempotencyKey)
+		fingerprint, _ := json.Marshal(item)
+		entry, state := idempotencyKeys.claim(keyID, string(fingerprint))
+		switch state {
+		case idempotencyReplay:
+			logger.Info("Replaying response for idempotency key", "idempotency_key", idempotencyKey)
+			itemOperationsTotal.WithLabelValues("create", "replayed").Inc()
+			c.Header("Location", entry.location)
+			c.Header("Idempotent-Replayed", "true")
+			respondJSON(c, entry.status, entry.body)
+			return
+		case idempotencyInFlight:
+			logger.Warn("Idempotency key is still being processed", "idempotency_key", idempotencyKey)
+			itemOperationsTotal.WithLabelValues("create", "conflict").Inc()
+			respondError(c, http.StatusConflict, "conflict", "A request with this Idempotency-Key is still being processed")
+			return
+		case idempotencyMismatch:
+			logger.Warn("Idempotency key reused with a different item", "idempotency_key", idempotencyKey)
+			itemOperationsTotal.WithLabelValues("create", "validation_error").Inc()
+			respondError(c, http.StatusUnprocessableEntity, "validation", "Idempotency-Key was already used with a different item")
+			return
+		}
+	}
+
+	itemID, err := store.Create(c.Request.Context(), item)
+	if err != nil {
+		logger.Error("Failed to create item", "item_name", item.Name, "error", err)
+		itemOperationsTotal.WithLabelValues("create", "error").Inc()
+		if idempotencyKey != "" {
+			idempotencyKeys.release(keyID)
+		}
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+	item.ID = itemID
+
+	logger.Info("Item created successfully", "item_id", itemID, "item_name", item.Name, "item_price", item.Price)
+	refreshPriceBuckets(c.Request.Context(), logger)
+	itemOperationsTotal.WithLabelValues("create", "success").Inc()
+	location := fmt.Sprintf("/items/%d", itemID)
+	body := gin.H{
+		"message": "Item created successfully",
+		"item_id": itemID,
+		"item":    item,
+	}
+	if idempotencyKey != "" {
+		idempotencyKeys.complete(keyID, http.StatusCreated, location, body)
+	}
+	c.Header("Location", location)
+	respondJSON(c, http.StatusCreated, body)
+}
+
+// batchItemResult reports the outcome of one item in a batch create
+type batchItemResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	ItemID int    `json:"item_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+} // @name BatchItemResult
+
+// @Summary  Create up to 100 items at once
+// @Tags     items
+// @Accept   json
+// @Produce  json
+// @Param    items body     []Item  true  "Items to create"
+// @Success  201   {object} batchResponse  "All items created"
+// @Success  207   {object} batchResponse  "Some items created"
+// @Failure  400   {object} batchResponse  "No items created"
+// @Failure  413   {object} errorResponse
+// @Failure  500   {object} errorResponse
+// @Security ApiKeyAuth
+// @Router   /items/batch [post]
+func createItemsBatch(c *gin.Context) {
+	logger := getLogger(c)
+
+	ctx, span := tracer.Start(c.Request.Context(), "items.batch_create")
+	defer span.End()
+
+	// Decode without binding so each item can be validated and reported on individually
+	var items []Item
+	if err := json.NewDecoder(c.Request.Body).Decode(&items); err != nil {
+		logger.Warn("Failed to decode JSON for batch create", "error", err.Error())
+		itemOperationsTotal.WithLabelValues("create", "bad_request").Inc()
+		if isBodyTooLarge(err) {
+			respondAPIError(c, http.StatusRequestEntityTooLarge, errBodyTooLarge)
+			return
+		}
+		// The decoder's message names Go types, so it stays in the log
+		respondError(c, http.StatusBadRequest, "bad_request", "Request body must be a JSON array of items")
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchSize {
+		logger.Warn("Rejected batch with invalid size", "batch_size", len(items))
+		itemOperationsTotal.WithLabelValues("create", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", fmt.Sprintf("batch must contain between 1 and %d items", maxBatchSize))
+		return
+	}
+	span.SetAttributes(attribute.Int("batch.size", len(items)))
+
+	results := make([]batchItemResult, len(items))
+	var valid []Item
+	var validIndexes []int
+	for i, item := range items {
+		err := binding.Validator.ValidateStruct(item)
+		if err == nil {
+			err = item.validate()
+		}
+		if err != nil {
+			message := err.Error()
+			if errs, ok := fieldErrors(err); ok {
+				messages := make([]string, 0, len(errs))
+				for _, e := range errs {
+					messages = append(messages, e.Error())
+				}
+				message = strings.Join(messages, "; ")
+			}
+			results[i] = batchItemResult{Index: i, Status: "error", Error: message}
+			itemOperationsTotal.WithLabelValues("create", "validation_error").Inc()
+			continue
+		}
+		valid = append(valid, item)
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid) > 0 {
+		ids, err := store.CreateMany(ctx, valid)
+		if err != nil {
+			logger.Error("Failed to create item batch", "batch_size", len(valid), "error", err)
+			itemOperationsTotal.WithLabelValues("create", "error").Add(float64(len(valid)))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+			return
+		}
+		for j, i := range validIndexes {
+			results[i] = batchItemResult{Index: i, Status: "created", ItemID: ids[j]}
+		}
+		refreshPriceBuckets(ctx, logger)
+		itemOperationsTotal.WithLabelValues("create", "success").Add(float64(len(ids)))
+	}
+
+	created := len(valid)
+	failed := len(items) - created
+	span.SetAttributes(attribute.Int("batch.created", created), attribute.Int("batch.failed", failed))
+	logger.Info("Item batch processed", "batch_size", len(items), "created", created, "failed", failed)
+
+	status := http.StatusMultiStatus
+	switch {
+	case failed == 0:
+		status = http.StatusCreated
+	case created == 0:
+		status = http.StatusBadRequest
+		recordError(c, "validation")
+	}
+	// The per-item results are the useful part even when every item failed, so a batch that
+	// created nothing keeps this body rather than the error envelope
+	respondJSON(c, status, gin.H{
+		"results": results,
+		"summary": gin.H{
+			"total":   len(items),
+			"created": created,
+			"failed":  failed,
+		},
+	})
+}
+
+// @Summary  Replace an item
+// @Tags     items
+// @Accept   json
+// @Produce  json
+// @Param    item_id path     int   true  "Item ID"
+// @Param    item    body     Item  true  "New item; item_id is ignored"
+// @Success  200     {object} itemResponse
+// @Failure  400     {object} errorResponse
+// @Failure  404     {object} errorResponse
+// @Failure  413     {object} errorResponse
+// @Failure  422     {object} errorResponse
+// @Failure  500     {object} errorResponse
+// @Security ApiKeyAuth
+// @Router   /items/{item_id} [put]
+func updateItem(c *gin.Context) {
+	logger := getLogger(c)
+
+	itemIDStr := c.Param("item_id")
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		logger.Warn("Invalid item ID", "item_id", itemIDStr, "error", err)
+		itemOperationsTotal.WithLabelValues("update", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", "Invalid item ID")
+		return
+	}
+
+	var item Item
+	if status, body, err := bindItem(c, &item); err != nil {
+		logger.Warn("Rejected invalid item on update", "item_id", itemID, "status", status, "error", err.Error())
+		itemOperationsTotal.WithLabelValues("update", operationStatus(status)).Inc()
+		respondAPIError(c, status, body)
+		return
+	}
+
+	item.ID = itemID
+	err = store.Update(c.Request.Context(), itemID, item)
+	if errors.Is(err, ErrItemNotFound) {
+		logger.Info("Item not found for update", "item_id", itemID)
+		itemOperationsTotal.WithLabelValues("update", "not_found").Inc()
+		respondError(c, http.StatusNotFound, "not_found", "Item not found")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to update item", "item_id", itemID, "error", err)
+		itemOperationsTotal.WithLabelValues("update", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	logger.Info("Item updated successfully", "item_id", itemID, "item_name", item.Name, "item_price", item.Price)
+	refreshPriceBuckets(c.Request.Context(), logger)
+	itemOperationsTotal.WithLabelValues("update", "success").Inc()
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "Item updated successfully",
+		"item_id": itemID,
+		"item":    item,
+	})
+}
+
+// patchItem merges a partial body into the stored item. The read and write aren't atomic, so a
+// concurrent update to the same item between them is overwritten.
+//
+// @Summary  Update some fields of an item
+// @Tags     items
+// @Accept   json
+// @Produce  json
+// @Param    item_id path     int        true  "Item ID"
+// @Param    patch   body     itemPatch  true  "Fields to change"
+// @Success  200     {object} itemResponse
+// @Failure  400     {object} errorResponse
+// @Failure  404     {object} errorResponse
+// @Failure  413     {object} errorResponse
+// @Failure  422     {object} errorResponse
+// @Failure  500     {object} errorResponse
+// @Security ApiKeyAuth
+// @Router   /items/{item_id} [patch]
+func patchItem(c *gin.Context) {
+	logger := getLogger(c)
+
+	itemIDStr := c.Param("item_id")
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		logger.Warn("Invalid item ID", "item_id", itemIDStr, "error", err)
+		itemOperationsTotal.WithLabelValues("patch", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", "Invalid item ID")
+		return
+	}
+
+	var patch itemPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		status, body := bindError(err)
+		logger.Warn("Rejected invalid item patch", "item_id", itemID, "status", status, "error", err.Error())
+		itemOperationsTotal.WithLabelValues("patch", operationStatus(status)).Inc()
+		respondAPIError(c, status, body)
+		return
+	}
+
+	existing, err := store.Get(c.Request.Context(), itemID)
+	if errors.Is(err, ErrItemNotFound) {
+		logger.Info("Item not found for patch", "item_id", itemID)
+		itemOperationsTotal.WithLabelValues("patch", "not_found").Inc()
+		respondError(c, http.StatusNotFound, "not_found", "Item not found")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to read item for patch", "item_id", itemID, "error", err)
+		itemOperationsTotal.WithLabelValues("patch", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	// Validate the merged item so the patch is held to the same rules as a full update
+	item := patch.apply(existing)
+	err = binding.Validator.ValidateStruct(item)
+	if err == nil {
+		err = item.validate()
+	}
+	if err != nil {
+		errs, _ := fieldErrors(err)
+		logger.Warn("Rejected invalid item patch", "item_id", itemID, "status", http.StatusUnprocessableEntity, "error", err.Error())
+		itemOperationsTotal.WithLabelValues("patch", "validation_error").Inc()
+		respondAPIError(c, http.StatusUnprocessableEntity, validationError(errs))
+		return
+	}
+
+	err = store.Update(c.Request.Context(), itemID, item)
+	if errors.Is(err, ErrItemNotFound) {
+		logger.Info("Item not found for patch", "item_id", itemID)
+		itemOperationsTotal.WithLabelValues("patch", "not_found").Inc()
+		respondError(c, http.StatusNotFound, "not_found", "Item not found")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to patch item", "item_id", itemID, "error", err)
+		itemOperationsTotal.WithLabelValues("patch", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	logger.Info("Item patched successfully", "item_id", itemID, "item_name", item.Name, "item_price", item.Price)
+	refreshPriceBuckets(c.Request.Context(), logger)
+	itemOperationsTotal.WithLabelValues("patch", "success").Inc()
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "Item patched successfully",
+		"item_id": itemID,
+		"item":    item,
+	})
+}
+
+// @Summary  Delete an item
+// @Tags     items
+// @Produce  json
+// @Param    item_id path int true "Item ID"
+// @Success  204
+// @Failure  400     {object} errorResponse
+// @Failure  404     {object} errorResponse
+// @Failure  500     {object} errorResponse
+// @Security ApiKeyAuth
+// @Router   /items/{item_id} [delete]
+func deleteItem(c *gin.Context) {
+	logger := getLogger(c)
+
+	itemIDStr := c.Param("item_id")
+	itemID, err := strconv.Atoi(itemIDStr)
+	if err != nil {
+		logger.Warn("Invalid item ID", "item_id", itemIDStr, "error", err)
+		itemOperationsTotal.WithLabelValues("delete", "bad_request").Inc()
+		respondError(c, http.StatusBadRequest, "bad_request", "Invalid item ID")
+		return
+	}
+
+	err = store.Delete(c.Request.Context(), itemID)
+	if errors.Is(err, ErrItemNotFound) {
+		logger.Info("Item not found for delete", "item_id", itemID)
+		itemOperationsTotal.WithLabelValues("delete", "not_found").Inc()
+		respondError(c, http.StatusNotFound, "not_found", "Item not found")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to delete item", "item_id", itemID, "error", err)
+		itemOperationsTotal.WithLabelValues("delete", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	logger.Info("Item deleted successfully", "item_id", itemID)
+	refreshPriceBuckets(c.Request.Context(), logger)
+	itemOperationsTotal.WithLabelValues("delete", "success").Inc()
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary  Delete every item
+// @Tags     items
+// @Produce  json
+// @Success  200 {object} deleteAllResponse
+// @Failure  500 {object} errorResponse
+// @Security ApiKeyAuth
+// @Router   /items/ [delete]
+func deleteAllItems(c *gin.Context) {
+	logger := getLogger(c)
+
+	removed, err := store.DeleteAll(c.Request.Context())
+	if err != nil {
+		logger.Error("Failed to delete all items", "error", err)
+		itemOperationsTotal.WithLabelValues("delete_all", "error").Inc()
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	logger.Warn("All items deleted", "items_removed", removed)
+	refreshPriceBuckets(c.Request.Context(), logger)
+	itemOperationsTotal.WithLabelValues("delete_all", "success").Inc()
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "All items deleted",
+		"removed": removed,
+	})
+}
+
+func getStatus(c *gin.Context) {
+	getLogger(c).Info("Health check performed")
+	respondJSON(c, http.StatusOK, gin.H{
+		"status":     "healthy",
+		"version":    version,
+		"commit":     commit,
+		"build_date": buildDate,
+	})
+}
+
+// versionInfo is the /version response; its fields are a stable contract for CI checks
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+}
+
+func getVersion(c *gin.Context) {
+	respondJSON(c, http.StatusOK, versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	})
+}
+
+func getLogLevel(c *gin.Context) {
+	respondJSON(c, http.StatusOK, gin.H{
+		"level": strings.ToLower(logLevel.Level().String()),
+	})
+}
+
+func setLogLevel(c *gin.Context) {
+	logger := getLogger(c)
+
+	var req struct {
+		Level string `json:"level" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		status, body := bindError(err)
+		logger.Warn("Failed to bind JSON for log level change", "status", status, "error", err.Error())
+		respondAPIError(c, status, body)
+		return
+	}
+
+	level, ok := logLevels[strings.ToLower(req.Level)]
+	if !ok {
+		logger.Warn("Rejected unknown log level", "level", req.Level)
+		respondError(c, http.StatusBadRequest, "bad_request", "level must be one of debug, info, warn, error")
+		return
+	}
+
+	oldLevel := logLevel.Level()
+	logLevel.Set(level)
+	logger.Warn("Log level changed", "old_level", oldLevel.String(), "new_level", level.String())
+
+	respondJSON(c, http.StatusOK, gin.H{
+		"level": strings.ToLower(level.String()),
 	})
 }
 
+// @Summary  Always fail with a 500
+// @Tags     errors
+// @Produce  json
+// @Failure  500 {object} errorResponse
+// @Router   /error-500 [get]
+func getError500(c *gin.Context) {
+	getLogger(c).Error("Simulating 500 Internal Server Error")
+	respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+}
+
+// @Summary  Always fail with a 400
+// @Tags     errors
+// @Produce  json
+// @Failure  400 {object} errorResponse
+// @Router   /error-400 [get]
 func getError400(c *gin.Context) {
 	getLogger(c).Warn("Simulating 400 Bad Request")
-	c.JSON(http.StatusBadRequest, gin.H{
-		"detail": "Bad Request",
+	respondError(c, http.StatusBadRequest, "bad_request", "Bad Request")
+}
+
+func getSlow(c *gin.Context) {
+	logger := getLogger(c)
+
+	ms, err := strconv.Atoi(c.DefaultQuery("ms", "500"))
+	if err != nil || ms < 0 {
+		logger.Warn("Invalid ms query param", "ms", c.Query("ms"))
+		respondError(c, http.StatusBadRequest, "bad_request", "ms must be a non-negative integer")
+		return
+	}
+	delay := time.Duration(ms) * time.Millisecond
+	if delay > maxSimulatedLatency {
+		delay = maxSimulatedLatency
+	}
+
+	// Give up early if the client disconnects or REQUEST_TIMEOUT fires
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.Request.Context().Done():
+		logger.Warn("Simulated latency aborted", "delay_ms", delay.Milliseconds(), "error", c.Request.Context().Err())
+		return
+	}
+
+	logger.Info("Simulated latency", "delay_ms", delay.Milliseconds())
+	respondJSON(c, http.StatusOK, gin.H{
+		"message":  "Slow response",
+		"delay_ms": delay.Milliseconds(),
+	})
+}
+
+func getFlaky(c *gin.Context) {
+	logger := getLogger(c)
+
+	failureRate, err := strconv.ParseFloat(c.DefaultQuery("rate", "0.5"), 64)
+	if err != nil || failureRate < 0 || failureRate > 1 {
+		logger.Warn("Invalid rate query param", "rate", c.Query("rate"))
+		respondError(c, http.StatusBadRequest, "bad_request", "rate must be a number between 0 and 1")
+		return
+	}
+
+	if rand.Float64() < failureRate {
+		logger.Error("Simulating flaky failure", "rate", failureRate)
+		respondError(c, http.StatusInternalServerError, "internal", "Internal Server Error")
+		return
+	}
+
+	logger.Info("Flaky request succeeded", "rate", failureRate)
+	respondJSON(c, http.StatusOK, gin.H{
+		"message": "Flaky request succeeded",
+		"rate":    failureRate,
+	})
+}
+
+func getBurn(c *gin.Context) {
+	logger := getLogger(c)
+
+	ms, err := strconv.Atoi(c.DefaultQuery("ms", "1000"))
+	if err != nil || ms < 0 {
+		logger.Warn("Invalid ms query param", "ms", c.Query("ms"))
+		respondError(c, http.StatusBadRequest, "bad_request", "ms must be a non-negative integer")
+		return
+	}
+	workers, err := strconv.Atoi(c.DefaultQuery("workers", "1"))
+	if err != nil || workers < 1 || workers > maxBurnWorkers {
+		logger.Warn("Invalid workers query param", "workers", c.Query("workers"))
+		respondError(c, http.StatusBadRequest, "bad_request", fmt.Sprintf("workers must be between 1 and %d", maxBurnWorkers))
+		return
+	}
+	duration := time.Duration(ms) * time.Millisecond
+	if duration > maxBurnDuration {
+		duration = maxBurnDuration
+	}
+
+	// Each worker spins until the deadline, bailing out if the client disconnects or REQUEST_TIMEOUT fires
+	ctx, cancel := context.WithTimeout(c.Request.Context(), duration)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer trackWorker("burn")()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := c.Request.Context().Err(); err != nil {
+		logger.Warn("CPU burn aborted", "duration_ms", duration.Milliseconds(), "workers", workers, "error", err)
+		return
+	}
+
+	logger.Info("Burned CPU", "duration_ms", duration.Milliseconds(), "workers", workers)
+	respondJSON(c, http.StatusOK, gin.H{
+		"message":     "CPU burn complete",
+		"duration_ms": duration.Milliseconds(),
+		"workers":     workers,
 	})
 }
\ No newline at end of file
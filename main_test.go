@@ -0,0 +1,572 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	if err := registerMetrics(registry); err != nil {
+		fmt.Fprintln(os.Stderr, "registering metrics:", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestRouter builds the app's router with env applied, a freshly seeded memory store and
+// empty idempotency keys, discarding its logs
+func newTestRouter(t *testing.T, env map[string]string) *gin.Engine {
+	t.Helper()
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	store = newMemoryItemStore()
+	idempotencyKeys = newIdempotencyStore(time.Hour)
+
+	router, err := newRouter(slog.New(slog.NewTextHandler(io.Discard, nil)), registry, "test", nil)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	return router
+}
+
+// serve sends req through router and returns the recorded response
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeError reads the error envelope from a JSON response body
+func decodeError(t *testing.T, body io.Reader) apiError {
+	t.Helper()
+	var envelope struct {
+		Error apiError `json:"error"`
+	}
+	if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+		t.Fatalf("decoding error body: %v", err)
+	}
+	return envelope.Error
+}
+
+// counterValue reads the current value of a counter
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := counter.Write(&m); err != nil {
+		t.Fatalf("reading counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// seriesCount counts the series a collector currently exports
+func seriesCount(collector prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+	n := 0
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func TestGzipKeepsErrorResponses(t *testing.T) {
+	router := newTestRouter(t, map[string]string{
+		"GZIP_ENABLED":    "true",
+		"REQUEST_TIMEOUT": "50ms",
+	})
+	router.GET("/test-panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		code   string
+	}{
+		{"timeout", "/slow?ms=1000", http.StatusServiceUnavailable, "timeout"},
+		{"panic", "/test-panic", http.StatusInternalServerError, "panic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := strings.SplitN(tt.path, "?", 2)[0]
+			before := counterValue(t, httpRequestsTotal.WithLabelValues(http.MethodGet, route, fmt.Sprint(tt.status)))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := serve(router, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+				t.Fatalf("Content-Encoding = %q, want gzip", got)
+			}
+			body, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("reading gzip body: %v", err)
+			}
+			if got := decodeError(t, body); got.Code != tt.code {
+				t.Errorf("error code = %q, want %q", got.Code, tt.code)
+			}
+			after := counterValue(t, httpRequestsTotal.WithLabelValues(http.MethodGet, route, fmt.Sprint(tt.status)))
+			if after != before+1 {
+				t.Errorf("http_requests_total{status=%q} rose by %v, want 1", fmt.Sprint(tt.status), after-before)
+			}
+		})
+	}
+}
+
+func TestSearchXML(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/search/?limit=2", nil)
+	req.Header.Set("Accept", "application/xml")
+	rec := serve(router, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Body.String(), "<search><search_results><result><name>laptop</name>") {
+		t.Errorf("unexpected XML layout: %s", rec.Body)
+	}
+	var response struct {
+		SearchResults []searchResult `xml:"search_results>result"`
+		Total         int            `xml:"total"`
+		Limit         int            `xml:"limit"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding XML: %v", err)
+	}
+	if len(response.SearchResults) != 2 || response.Total != len(allItems) || response.Limit != 2 {
+		t.Errorf("got %d results, total %d, limit %d; want 2, %d, 2", len(response.SearchResults), response.Total, response.Limit, len(allItems))
+	}
+}
+
+// captureStdout redirects os.Stdout to a file for the rest of the test, returning a function
+// that reads what was written so far
+func captureStdout(t *testing.T) func() string {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := os.Stdout
+	os.Stdout = f
+	t.Cleanup(func() {
+		os.Stdout = original
+		f.Close()
+	})
+	return func() string {
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+}
+
+func TestNewLogFileWriterCreatesFile(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	if _, err := newLogFileWriter(dir, "app.log"); err != nil {
+		t.Fatalf("newLogFileWriter: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app.log")); err != nil {
+		t.Errorf("log file not created: %v", err)
+	}
+}
+
+func TestNewSlogLoggerWritesToLogDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	t.Setenv("LOG_FILE", "test.log")
+	captureStdout(t)
+
+	newSlogLogger().Info("written to file")
+
+	data, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatalf("reading log file: %v", err)
+	}
+	if !strings.Contains(string(data), "written to file") {
+		t.Errorf("log file = %q, want the logged message", data)
+	}
+}
+
+func TestNewSlogLoggerFallsBackToStdout(t *testing.T) {
+	// A directory can't be created beneath a regular file, even by root
+	blocker := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(blocker, "logs")
+	t.Setenv("LOG_DIR", dir)
+	if _, err := newLogFileWriter(dir, "app.log"); err == nil {
+		t.Fatal("newLogFileWriter succeeded beneath a regular file")
+	}
+	stdout := captureStdout(t)
+
+	newSlogLogger().Info("written to stdout")
+
+	out := stdout()
+	for _, want := range []string{"File logging disabled", "written to stdout"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("stdout = %q, want it to contain %q", out, want)
+		}
+	}
+}
+
+// sendJSON sends body to router as a JSON request
+func sendJSON(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return serve(router, req)
+}
+
+func TestCreateItemValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{"negative price", `{"name": "widget", "price": -5}`, http.StatusUnprocessableEntity, "validation", "price"},
+		{"missing name", `{"price": 10}`, http.StatusUnprocessableEntity, "validation", "name"},
+		{"missing price", `{"name": "widget"}`, http.StatusUnprocessableEntity, "validation", "price"},
+		{"invalid JSON", `{"name": "widget",`, http.StatusBadRequest, "bad_request", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, nil)
+
+			rec := sendJSON(router, http.MethodPost, "/items/", tt.body)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			got := decodeError(t, rec.Body)
+			if got.Code != tt.code {
+				t.Errorf("error code = %q, want %q", got.Code, tt.code)
+			}
+			if tt.field != "" && (len(got.Details) == 0 || got.Details[0].Field != tt.field) {
+				t.Errorf("details = %+v, want the first on %q", got.Details, tt.field)
+			}
+			if count, _ := store.Count(context.Background()); count != len(seedItems) {
+				t.Errorf("store holds %d items, want %d", count, len(seedItems))
+			}
+		})
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		status        int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"missing token", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer wrong", http.StatusUnauthorized},
+		{"not a bearer token", "s3cret", "s3cret", http.StatusUnauthorized},
+		{"valid token", "s3cret", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, map[string]string{"METRICS_TOKEN": tt.token})
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := serve(router, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+// logLine sets env, logs one message through newSlogLogger and returns what reached the log file
+func logLine(t *testing.T, env map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("LOG_DIR", dir)
+	t.Setenv("LOG_FILE", "test.log")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	captureStdout(t)
+
+	newSlogLogger().Info("hello", "answer", 42)
+
+	data, err := os.ReadFile(filepath.Join(dir, "test.log"))
+	if err != nil {
+		t.Fatalf("reading log file: %v", err)
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func TestLogFormat(t *testing.T) {
+	if line := logLine(t, map[string]string{"LOG_FORMAT": "json"}); !json.Valid([]byte(line)) {
+		t.Errorf("LOG_FORMAT=json wrote %q, want JSON", line)
+	}
+
+	line := logLine(t, map[string]string{"LOG_FORMAT": "text"})
+	if json.Valid([]byte(line)) {
+		t.Errorf("LOG_FORMAT=text wrote JSON: %q", line)
+	}
+	if !strings.Contains(line, "msg=hello") || !strings.Contains(line, "answer=42") {
+		t.Errorf("LOG_FORMAT=text wrote %q, want key=value pairs", line)
+	}
+}
+
+func TestLogSource(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		t.Run(strconv.FormatBool(enabled), func(t *testing.T) {
+			line := logLine(t, map[string]string{"LOG_SOURCE": strconv.FormatBool(enabled)})
+
+			var record map[string]any
+			if err := json.Unmarshal([]byte(line), &record); err != nil {
+				t.Fatalf("decoding log line %q: %v", line, err)
+			}
+			source, ok := record[slog.SourceKey].(map[string]any)
+			if ok != enabled {
+				t.Fatalf("source attribute present = %v, want %v: %q", ok, enabled, line)
+			}
+			if enabled && !strings.HasSuffix(fmt.Sprint(source["file"]), "main_test.go") {
+				t.Errorf("source file = %v, want the logging call in main_test.go", source["file"])
+			}
+		})
+	}
+}
+
+func TestUnmatchedRoutesShareOneSeries(t *testing.T) {
+	router := newTestRouter(t, nil)
+	httpRequestsTotal.Reset()
+
+	const flood = 50
+	for i := 0; i < flood; i++ {
+		path := fmt.Sprintf("/random-%d/%d", rand.Int(), i)
+		if rec := serve(router, httptest.NewRequest(http.MethodGet, path, nil)); rec.Code != http.StatusNotFound {
+			t.Fatalf("GET %s: status = %d, want 404", path, rec.Code)
+		}
+	}
+
+	if series := seriesCount(httpRequestsTotal); series != 1 {
+		t.Errorf("http_requests_total has %d series, want 1", series)
+	}
+	if got := counterValue(t, httpRequestsTotal.WithLabelValues(http.MethodGet, unknownHandler, "404")); got != flood {
+		t.Errorf(`http_requests_total{handler="unknown"} = %v, want %d`, got, flood)
+	}
+}
+
+func TestMetricsReset(t *testing.T) {
+	t.Run("absent unless enabled", func(t *testing.T) {
+		router := newTestRouter(t, nil)
+		if rec := serve(router, httptest.NewRequest(http.MethodPost, "/metrics/reset", nil)); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", rec.Code)
+		}
+	})
+
+	router := newTestRouter(t, map[string]string{
+		"ALLOW_METRICS_RESET": "true",
+		"API_KEYS":            "k1",
+	})
+	reset := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/metrics/reset", nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		return serve(router, req)
+	}
+
+	t.Run("requires an API key", func(t *testing.T) {
+		if rec := reset(""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("without a key: status = %d, want 401", rec.Code)
+		}
+		if rec := reset("wrong"); rec.Code != http.StatusForbidden {
+			t.Errorf("with a wrong key: status = %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("zeroes counters", func(t *testing.T) {
+		serve(router, httptest.NewRequest(http.MethodGet, "/error-400", nil))
+		serve(router, httptest.NewRequest(http.MethodGet, "/items/1", nil))
+		if seriesCount(appErrorsTotal) == 0 || seriesCount(itemOperationsTotal) == 0 {
+			t.Fatal("expected error and item operation series before the reset")
+		}
+
+		if rec := reset("k1"); rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+
+		// Request metrics aren't checked, since the reset request itself is counted afterwards
+		for name, metric := range map[string]prometheus.Collector{
+			"app_errors_total":      appErrorsTotal,
+			"item_operations_total": itemOperationsTotal,
+		} {
+			if series := seriesCount(metric); series != 0 {
+				t.Errorf("%s has %d series after the reset, want 0", name, series)
+			}
+		}
+	})
+}
+
+func TestSearchItemsByIDRangeWidth(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{"widest allowed", "from_id=1&to_id=1000", http.StatusOK},
+		{"one too wide", "from_id=1&to_id=1001", http.StatusBadRequest},
+		{"spanning the int range", "from_id=-9223372036854775808&to_id=9223372036854775807", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter(t, nil)
+			if rec := serve(router, httptest.NewRequest(http.MethodGet, "/search/id-range?"+tt.query, nil)); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
+
+func TestNoRouteUsesErrorEnvelope(t *testing.T) {
+	router := newTestRouter(t, nil)
+	counter := appErrorsTotal.WithLabelValues(unknownHandler, "not_found")
+	before := counterValue(t, counter)
+
+	rec := serve(router, httptest.NewRequest(http.MethodGet, "/no/such/path", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if got := decodeError(t, rec.Body); got.Code != "not_found" {
+		t.Errorf("error code = %q, want not_found", got.Code)
+	}
+	if got := counterValue(t, counter) - before; got != 1 {
+		t.Errorf(`app_errors_total{handler="unknown",category="not_found"} rose by %v, want 1`, got)
+	}
+}
+
+func TestCursorPaginationAdvances(t *testing.T) {
+	for _, tt := range []struct{ path, results string }{
+		{"/items/", "items"},
+		{"/search/", "search_results"},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			router := newTestRouter(t, nil)
+
+			if rec := serve(router, httptest.NewRequest(http.MethodGet, tt.path+"?pagination=cursor&limit=0", nil)); rec.Code != http.StatusBadRequest {
+				t.Errorf("limit=0: status = %d, want 400", rec.Code)
+			}
+
+			seen, total, after := 0, 0, 0
+			cursor := ""
+			for page := 0; ; page++ {
+				if page > len(allItems)+len(seedItems) {
+					t.Fatal("next_cursor never ran out")
+				}
+				rec := serve(router, httptest.NewRequest(http.MethodGet, tt.path+"?pagination=cursor&limit=1&cursor="+cursor, nil))
+				if rec.Code != http.StatusOK {
+					t.Fatalf("page %d: status = %d, want 200: %s", page, rec.Code, rec.Body)
+				}
+				var body map[string]any
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatalf("page %d: decoding body: %v", page, err)
+				}
+				seen += len(body[tt.results].([]any))
+				total = int(body["total"].(float64))
+
+				next, _ := body["next_cursor"].(string)
+				if next == "" {
+					break
+				}
+				decoded, err := base64.RawURLEncoding.DecodeString(next)
+				if err != nil {
+					t.Fatalf("page %d: next_cursor %q isn't base64url: %v", page, next, err)
+				}
+				position, _ := strconv.Atoi(string(decoded))
+				if position <= after {
+					t.Fatalf("page %d: next_cursor points at %d, not past %d", page, position, after)
+				}
+				cursor, after = next, position
+			}
+			if seen != total {
+				t.Errorf("pages returned %d entries, want %d", seen, total)
+			}
+		})
+	}
+}
+
+func TestCreateItemsBatchRejectsNonArray(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	rec := sendJSON(router, http.MethodPost, "/items/batch", `{"name": "widget", "price": 10}`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	got := decodeError(t, rec.Body)
+	if got.Code != "bad_request" || got.Message != "Request body must be a JSON array of items" {
+		t.Errorf("error = %+v, want the fixed bad_request message", got)
+	}
+	if strings.Contains(got.Message, "main.") || strings.Contains(got.Message, "json:") {
+		t.Errorf("message %q leaks decoder internals", got.Message)
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	router := newTestRouter(t, nil)
+	defer logLevel.Set(logLevel.Level())
+	logLevel.Set(slog.LevelInfo)
+
+	for _, level := range []string{"info+2", "warn-3", "ERROR-1", "verbose"} {
+		rec := sendJSON(router, http.MethodPut, "/admin/loglevel", `{"level": "`+level+`"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("level %q: status = %d, want 400", level, rec.Code)
+		}
+		if got := logLevel.Level(); got != slog.LevelInfo {
+			t.Errorf("level %q: log level changed to %v", level, got)
+		}
+	}
+
+	rec := sendJSON(router, http.MethodPut, "/admin/loglevel", `{"level": "DEBUG"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("level DEBUG: status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if got := logLevel.Level(); got != slog.LevelDebug {
+		t.Errorf("log level = %v, want DEBUG", got)
+	}
+}
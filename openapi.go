@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/dimasyotama/go-observability-dashboard/docs"
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// The API description in docs/ is generated from the annotations on the handlers; rerun
+// go generate after changing a route, parameter or response.
+//go:generate go run github.com/swaggo/swag/cmd/swag@v1.16.3 init --generalInfo openapi.go --output docs --outputTypes go,json
+
+// @title                      Go Observability Dashboard API
+// @version                    1.0
+// @description                Demo item catalog instrumented with Prometheus metrics, OpenTelemetry traces and structured logs.
+// @BasePath                   /
+// @securityDefinitions.apikey ApiKeyAuth
+// @in                         header
+// @name                       X-API-Key
+// @description                Required on write endpoints when API_KEYS is set.
+
+// Response schemas for the API description. Handlers build their bodies with gin.H, so these
+// types only describe them and must be kept in step when a response changes.
+
+type errorResponse struct {
+	Error apiError `json:"error"`
+} // @name ErrorResponse
+
+type itemResponse struct {
+	Message string `json:"message" example:"Item created successfully"`
+	ItemID  int    `json:"item_id" example:"4"`
+	Item    Item   `json:"item"`
+} // @name ItemResponse
+
+type itemListResponse struct {
+	Items      []Item `json:"items"`
+	Total      int    `json:"total" example:"3"`
+	Limit      int    `json:"limit" example:"20"`
+	Offset     int    `json:"offset,omitempty" example:"0"`
+	NextCursor string `json:"next_cursor,omitempty" example:"Mg"`
+} // @name ItemList
+
+type idRangeResponse struct {
+	Items []Item `json:"items"`
+	Total int    `json:"total" example:"3"`
+} // @name IDRangeResponse
+
+type batchResponse struct {
+	Results []batchItemResult `json:"results"`
+	Summary struct {
+		Total   int `json:"total" example:"2"`
+		Created int `json:"created" example:"1"`
+		Failed  int `json:"failed" example:"1"`
+	} `json:"summary"`
+} // @name BatchResponse
+
+type deleteAllResponse struct {
+	Message string `json:"message" example:"All items deleted"`
+	Removed int    `json:"removed" example:"3"`
+} // @name DeleteAllResponse
+
+// getOpenAPI serves the generated API description
+func getOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(docs.SwaggerInfo.ReadDoc()))
+}
+
+// docsHandler serves Swagger UI, pointed at /openapi.json, under a /*any route
+func docsHandler() gin.HandlerFunc {
+	ui := ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/openapi.json"))
+	return func(c *gin.Context) {
+		if c.Param("any") == "/" {
+			c.Redirect(http.StatusMovedPermanently, "index.html")
+			return
+		}
+		ui(c)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"google.golang.org/grpc"
+)
+
+// otelHTTPMetrics mirrors the key Prometheus HTTP metrics as OTel instruments
+type otelHTTPMetrics struct {
+	requests metric.Int64Counter
+	duration metric.Float64Histogram
+}
+
+// initMeterProvider exports OTel metrics over conn on a periodic reader
+// (interval set by OTEL_METRIC_EXPORT_INTERVAL, default 60s)
+func initMeterProvider(ctx context.Context, conn *grpc.ClientConn, serviceName string) (*sdkmetric.MeterProvider, *otelHTTPMetrics, error) {
+	exporter, err := otlpmetricgrpc.New(ctx, otlpmetricgrpc.WithGRPCConn(conn))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create OTLP metric exporter: %w", err)
+	}
+
+	res, err := newResource(ctx, serviceName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mp := sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)),
+		sdkmetric.WithResource(res),
+	)
+	otel.SetMeterProvider(mp)
+
+	meter := mp.Meter("github.com/dimasyotama/go-observability-dashboard")
+	requests, err := meter.Int64Counter("http.server.request.count",
+		metric.WithDescription("Total number of HTTP requests"),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request counter: %w", err)
+	}
+	duration, err := meter.Float64Histogram("http.server.duration",
+		metric.WithDescription("HTTP request duration in seconds"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create duration histogram: %w", err)
+	}
+
+	return mp, &otelHTTPMetrics{requests: requests, duration: duration}, nil
+}
+
+// otelMetricsMiddleware records request count and duration on the OTel instruments
+func otelMetricsMiddleware(m *otelHTTPMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := handlerLabel(c)
+		attrs := metric.WithAttributes(
+			attribute.String("http.method", c.Request.Method),
+			attribute.String("http.route", path),
+			attribute.Int("http.status_code", c.Writer.Status()),
+		)
+
+		ctx := c.Request.Context()
+		m.requests.Add(ctx, 1, attrs)
+		m.duration.Record(ctx, time.Since(start).Seconds(), attrs)
+	}
+}
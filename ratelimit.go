@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitExemptPaths are never throttled so scrapes and probes keep working under load
+var rateLimitExemptPaths = map[string]bool{
+	"/metrics": true,
+	"/status":  true,
+	"/healthz": true,
+}
+
+// clientLimiter is a token bucket for a single client IP
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter hands out one token bucket per client IP, evicting idle ones
+type ipRateLimiter struct {
+	mu       sync.Mutex
+	clients  map[string]*clientLimiter
+	limit    rate.Limit
+	burst    int
+	idleTTL  time.Duration
+	lastScan time.Time
+}
+
+func newIPRateLimiter(limit rate.Limit, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		clients:  make(map[string]*clientLimiter),
+		limit:    limit,
+		burst:    burst,
+		idleTTL:  3 * time.Minute,
+		lastScan: time.Now(),
+	}
+}
+
+// get returns the limiter for ip, creating it on first use
+func (l *ipRateLimiter) get(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	evictStale(l.clients, &l.lastScan, l.idleTTL, now, func(client *clientLimiter) bool {
+		return now.Sub(client.lastSeen) > l.idleTTL
+	})
+
+	client, exists := l.clients[ip]
+	if !exists {
+		client = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = now
+	return client.limiter
+}
+
+// rateLimitMiddleware throttles each client IP with a token bucket, answering 429 when it's empty
+func rateLimitMiddleware(limiter *ipRateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rateLimitExemptPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		reservation := limiter.get(c.ClientIP()).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+
+			getLogger(c).Warn("Rate limit exceeded", "retry_after", delay.String())
+			httpRateLimitedTotal.Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			respondError(c, http.StatusTooManyRequests, "rate_limited", "Too Many Requests")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	const burst = 5
+	router := newTestRouter(t, map[string]string{
+		"RATE_LIMIT_RPS":   "0.01",
+		"RATE_LIMIT_BURST": strconv.Itoa(burst),
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		return serve(router, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	for i := 0; i < burst; i++ {
+		if rec := get("/items/"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i+1, rec.Code)
+		}
+	}
+
+	rec := get("/items/")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("request %d: status = %d, want 429", burst+1, rec.Code)
+	}
+	if retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || retryAfter < 1 {
+		t.Errorf("Retry-After = %q, want a positive number of seconds", rec.Header().Get("Retry-After"))
+	}
+	if got := decodeError(t, rec.Body); got.Code != "rate_limited" {
+		t.Errorf("error code = %q, want rate_limited", got.Code)
+	}
+
+	// The client's bucket is empty, but scrapes and probes are exempt
+	for _, path := range []string{"/metrics", "/status"} {
+		for i := 0; i < burst+1; i++ {
+			if rec := get(path); rec.Code != http.StatusOK {
+				t.Fatalf("%s request %d: status = %d, want 200", path, i+1, rec.Code)
+			}
+		}
+	}
+}
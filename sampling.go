@@ -0,0 +1,81 @@
+package main
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Head sampling decides when a span starts, before the response status is known, so errors
+// can't be sampled up front. Instead, with OTEL_TRACES_SAMPLE_ERRORS, errorAwareSampler keeps
+// every span recording and errorSpanProcessor exports the unsampled ones that ended in an
+// error. Only the failing spans themselves are rescued: their successful siblings and children
+// were already discarded, and upstream and downstream services still see the trace as
+// unsampled, so rescued traces are partial.
+
+// errorAwareSampler wraps a sampler, recording the spans it would drop instead of discarding
+// them so errorSpanProcessor can still see how they end
+type errorAwareSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s errorAwareSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s errorAwareSampler) Description() string {
+	return "ErrorAware{" + s.base.Description() + "}"
+}
+
+// errorSpanProcessor forwards sampled spans to the wrapped processor as usual, and unsampled
+// spans only when they ended in an error
+type errorSpanProcessor struct {
+	sdktrace.SpanProcessor
+}
+
+func (p errorSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		if !isErrorSpan(s) {
+			return
+		}
+		s = promotedSpan{ReadOnlySpan: s}
+	}
+	p.SpanProcessor.OnEnd(s)
+}
+
+// isErrorSpan reports whether a span failed: an error status (otelgin sets one for 5xx), a
+// 4xx or 5xx response code, or an error category recorded by recordError
+func isErrorSpan(s sdktrace.ReadOnlySpan) bool {
+	if s.Status().Code == codes.Error {
+		return true
+	}
+	for _, attr := range s.Attributes() {
+		switch attr.Key {
+		case "http.status_code":
+			if attr.Value.AsInt64() >= 400 {
+				return true
+			}
+		case errorCategoryKey:
+			return true
+		}
+	}
+	return false
+}
+
+// errorCategoryKey is the span attribute recordError sets on failing requests
+const errorCategoryKey = attribute.Key("error.category")
+
+// promotedSpan reports a recorded span as sampled so the batch processor exports it
+type promotedSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s promotedSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+// ErrItemNotFound is returned by an ItemStore when no item has the requested ID
+var ErrItemNotFound = errors.New("item not found")
+
+// ItemStore is the storage layer behind the item handlers. Items it returns carry their ID;
+// the ID on items passed to Create, CreateMany and Update is ignored.
+type ItemStore interface {
+	Get(ctx context.Context, id int) (Item, error)
+	Create(ctx context.Context, item Item) (int, error)
+	CreateMany(ctx context.Context, items []Item) ([]int, error)
+	List(ctx context.Context) ([]Item, error)
+	Update(ctx context.Context, id int, item Item) error
+	Delete(ctx context.Context, id int) error
+	DeleteAll(ctx context.Context) (int, error)
+	Count(ctx context.Context) (int, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// newItemStore builds the ItemStore selected by STORAGE_BACKEND (memory or sqlite)
+func newItemStore(ctx context.Context) (ItemStore, error) {
+	backend := getEnv("STORAGE_BACKEND", "memory")
+	switch backend {
+	case "memory":
+		return newTracedItemStore(newMemoryItemStore(), backend), nil
+	case "sqlite":
+		sqliteStore, err := newSQLiteItemStore(ctx, getEnv("SQLITE_PATH", "/app/data/items.db"))
+		if err != nil {
+			return nil, err
+		}
+		return newTracedItemStore(sqliteStore, backend), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
+
+// storeTracer records the store spans; backends use it directly for per-row spans inside a batch
+var storeTracer = otel.Tracer("github.com/dimasyotama/go-observability-dashboard/store")
+
+// tracedItemStore wraps an ItemStore, recording a child span for every operation
+type tracedItemStore struct {
+	next   ItemStore
+	system string
+}
+
+func newTracedItemStore(next ItemStore, system string) *tracedItemStore {
+	return &tracedItemStore{next: next, system: system}
+}
+
+// start opens a span named name for the given db operation
+func (s *tracedItemStore) start(ctx context.Context, name, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("db.system", s.system),
+		attribute.String("db.operation", operation),
+	)
+	return storeTracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endStoreSpan records err on the span and ends it; a missing item is an expected outcome, not a failure
+func endStoreSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrItemNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (s *tracedItemStore) Get(ctx context.Context, id int) (Item, error) {
+	ctx, span := s.start(ctx, "db.GetItem", "get", attribute.Int("item.id", id))
+	item, err := s.next.Get(ctx, id)
+	endStoreSpan(span, err)
+	return item, err
+}
+
+func (s *tracedItemStore) Create(ctx context.Context, item Item) (int, error) {
+	ctx, span := s.start(ctx, "db.CreateItem", "create")
+	id, err := s.next.Create(ctx, item)
+	if err == nil {
+		span.SetAttributes(attribute.Int("item.id", id))
+	}
+	endStoreSpan(span, err)
+	return id, err
+}
+
+func (s *tracedItemStore) CreateMany(ctx context.Context, items []Item) ([]int, error) {
+	ctx, span := s.start(ctx, "db.CreateItems", "create_many", attribute.Int("items.count", len(items)))
+	ids, err := s.next.CreateMany(ctx, items)
+	endStoreSpan(span, err)
+	return ids, err
+}
+
+// startInsertSpan opens the per-row span for the index-th insert of a batch
+func startInsertSpan(ctx context.Context, index int) (context.Context, trace.Span) {
+	return storeTracer.Start(ctx, "db.CreateItem", trace.WithAttributes(
+		attribute.String("db.operation", "create"),
+		attribute.Int("batch.index", index),
+	))
+}
+
+func (s *tracedItemStore) List(ctx context.Context) ([]Item, error) {
+	ctx, span := s.start(ctx, "db.ListItems", "list")
+	items, err := s.next.List(ctx)
+	span.SetAttributes(attribute.Int("items.count", len(items)))
+	endStoreSpan(span, err)
+	return items, err
+}
+
+func (s *tracedItemStore) Update(ctx context.Context, id int, item Item) error {
+	ctx, span := s.start(ctx, "db.UpdateItem", "update", attribute.Int("item.id", id))
+	err := s.next.Update(ctx, id, item)
+	endStoreSpan(span, err)
+	return err
+}
+
+func (s *tracedItemStore) Delete(ctx context.Context, id int) error {
+	ctx, span := s.start(ctx, "db.DeleteItem", "delete", attribute.Int("item.id", id))
+	err := s.next.Delete(ctx, id)
+	endStoreSpan(span, err)
+	return err
+}
+
+func (s *tracedItemStore) DeleteAll(ctx context.Context) (int, error) {
+	ctx, span := s.start(ctx, "db.DeleteAllItems", "delete_all")
+	removed, err := s.next.DeleteAll(ctx)
+	span.SetAttributes(attribute.Int("items.count", removed))
+	endStoreSpan(span, err)
+	return removed, err
+}
+
+// Count isn't traced; it backs the items_total gauge, so it runs on every scrape
+func (s *tracedItemStore) Count(ctx context.Context) (int, error) {
+	return s.next.Count(ctx)
+}
+
+func (s *tracedItemStore) Ping(ctx context.Context) error {
+	return s.next.Ping(ctx)
+}
+
+func (s *tracedItemStore) Close() error {
+	return s.next.Close()
+}
+
+// memoryItemStore is an in-memory item map guarded for concurrent handler access
+type memoryItemStore struct {
+	sync.RWMutex
+	items  map[int]Item
+	nextID int
+}
+
+// newMemoryItemStore creates a memory store holding the seed items
+func newMemoryItemStore() *memoryItemStore {
+	s := &memoryItemStore{items: make(map[int]Item), nextID: 1}
+	for _, item := range seedItems {
+		item.ID = s.nextID
+		s.items[item.ID] = item
+		s.nextID++
+	}
+	return s
+}
+
+func (s *memoryItemStore) Get(_ context.Context, id int) (Item, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	item, exists := s.items[id]
+	if !exists {
+		return Item{}, ErrItemNotFound
+	}
+	return item, nil
+}
+
+func (s *memoryItemStore) Create(_ context.Context, item Item) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	item.ID = s.nextID
+	s.items[item.ID] = item
+	s.nextID++
+	return item.ID, nil
+}
+
+func (s *memoryItemStore) CreateMany(ctx context.Context, items []Item) ([]int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ids := make([]int, 0, len(items))
+	for i, item := range items {
+		_, span := startInsertSpan(ctx, i)
+		item.ID = s.nextID
+		s.items[item.ID] = item
+		s.nextID++
+		span.SetAttributes(attribute.Int("item.id", item.ID))
+		span.End()
+		ids = append(ids, item.ID)
+	}
+	return ids, nil
+}
+
+// List returns every item ordered by ID, matching the sqlite backend
+func (s *memoryItemStore) List(_ context.Context) ([]Item, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	items := make([]Item, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ID < items[j].ID
+	})
+	return items, nil
+}
+
+func (s *memoryItemStore) Update(_ context.Context, id int, item Item) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.items[id]; !exists {
+		return ErrItemNotFound
+	}
+	item.ID = id
+	s.items[id] = item
+	return nil
+}
+
+func (s *memoryItemStore) Delete(_ context.Context, id int) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, exists := s.items[id]; !exists {
+		return ErrItemNotFound
+	}
+	delete(s.items, id)
+	return nil
+}
+
+func (s *memoryItemStore) DeleteAll(_ context.Context) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	removed := len(s.items)
+	s.items = make(map[int]Item)
+	return removed, nil
+}
+
+func (s *memoryItemStore) Count(_ context.Context) (int, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return len(s.items), nil
+}
+
+func (s *memoryItemStore) Ping(_ context.Context) error {
+	return nil
+}
+
+func (s *memoryItemStore) Close() error {
+	return nil
+}
+
+// sqliteMigrations are applied in order on startup; append new steps, never edit old ones
+var sqliteMigrations = []string{
+	`CREATE TABLE items (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		name     TEXT    NOT NULL,
+		price    REAL    NOT NULL,
+		is_offer INTEGER
+	)`,
+}
+
+// sqliteItemStore persists items in a SQLite database
+type sqliteItemStore struct {
+	db *sql.DB
+}
+
+// newSQLiteItemStore opens the database at path, applies migrations and seeds an empty table
+func newSQLiteItemStore(ctx context.Context, path string) (*sqliteItemStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite allows a single writer; serialize access instead of surfacing SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	s := &sqliteItemStore{db: db}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := s.seed(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies any sqliteMigrations not yet recorded in schema_migrations
+func (s *sqliteItemStore) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var applied int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for version := applied; version < len(sqliteMigrations); version++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, sqliteMigrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", version+1, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES (?)`, version+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", version+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", version+1, err)
+		}
+	}
+	return nil
+}
+
+// seed inserts the seed items when the items table is empty
+func (s *sqliteItemStore) seed(ctx context.Context) error {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM items`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count items: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for _, item := range seedItems {
+		if _, err := s.Create(ctx, item); err != nil {
+			return fmt.Errorf("failed to seed items: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *sqliteItemStore) Get(ctx context.Context, id int) (Item, error) {
+	item := Item{ID: id}
+	var isOffer sql.NullBool
+	err := s.db.QueryRowContext(ctx, `SELECT name, price, is_offer FROM items WHERE id = ?`, id).
+		Scan(&item.Name, &item.Price, &isOffer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Item{}, ErrItemNotFound
+	}
+	if err != nil {
+		return Item{}, fmt.Errorf("failed to get item %d: %w", id, err)
+	}
+	if isOffer.Valid {
+		item.IsOffer = &isOffer.Bool
+	}
+	return item, nil
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insertItem inserts item using db, returning its generated ID
+func insertItem(ctx context.Context, db sqlExecer, item Item) (int, error) {
+	res, err := db.ExecContext(ctx, `INSERT INTO items (name, price, is_offer) VALUES (?, ?, ?)`,
+		item.Name, item.Price, item.IsOffer)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create item: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read created item ID: %w", err)
+	}
+	return int(id), nil
+}
+
+func (s *sqliteItemStore) Create(ctx context.Context, item Item) (int, error) {
+	return insertItem(ctx, s.db, item)
+}
+
+// CreateMany inserts all items in a single transaction; either every item is created or none is
+func (s *sqliteItemStore) CreateMany(ctx context.Context, items []Item) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin batch insert: %w", err)
+	}
+
+	ids := make([]int, 0, len(items))
+	for i, item := range items {
+		insertCtx, span := startInsertSpan(ctx, i)
+		id, err := insertItem(insertCtx, tx, item)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+			tx.Rollback()
+			return nil, err
+		}
+		span.SetAttributes(attribute.Int("item.id", id))
+		span.End()
+		ids = append(ids, id)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit batch insert: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *sqliteItemStore) List(ctx context.Context) ([]Item, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, name, price, is_offer FROM items ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		var item Item
+		var isOffer sql.NullBool
+		if err := rows.Scan(&item.ID, &item.Name, &item.Price, &isOffer); err != nil {
+			return nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		if isOffer.Valid {
+			item.IsOffer = &isOffer.Bool
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list items: %w", err)
+	}
+	return items, nil
+}
+
+func (s *sqliteItemStore) Update(ctx context.Context, id int, item Item) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE items SET name = ?, price = ?, is_offer = ? WHERE id = ?`,
+		item.Name, item.Price, item.IsOffer, id)
+	if err != nil {
+		return fmt.Errorf("failed to update item %d: %w", id, err)
+	}
+	return checkRowAffected(res)
+}
+
+func (s *sqliteItemStore) Delete(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM items WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete item %d: %w", id, err)
+	}
+	return checkRowAffected(res)
+}
+
+func (s *sqliteItemStore) DeleteAll(ctx context.Context) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM items`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete all items: %w", err)
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	return int(removed), nil
+}
+
+func (s *sqliteItemStore) Count(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM items`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count items: %w", err)
+	}
+	return count, nil
+}
+
+func (s *sqliteItemStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqliteItemStore) Close() error {
+	return s.db.Close()
+}
+
+// checkRowAffected maps a write that touched no rows to ErrItemNotFound
+func checkRowAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if n == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
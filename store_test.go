@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestMemoryItemStoreConcurrentAccess is meant to run under go test -race
+func TestMemoryItemStoreConcurrentAccess(t *testing.T) {
+	s := newMemoryItemStore()
+	ctx := context.Background()
+
+	const workers = 8
+	const rounds = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				id, err := s.Create(ctx, Item{Name: fmt.Sprintf("item-%d-%d", w, i), Price: 1})
+				if err != nil {
+					errs <- fmt.Errorf("Create: %w", err)
+					return
+				}
+				if _, err := s.Get(ctx, id); err != nil {
+					errs <- fmt.Errorf("Get(%d): %w", id, err)
+					return
+				}
+				if err := s.Update(ctx, id, Item{ID: id, Name: "updated", Price: 2}); err != nil {
+					errs <- fmt.Errorf("Update(%d): %w", id, err)
+					return
+				}
+				// Reads of the seed items race with the writes above
+				if _, err := s.Get(ctx, 1); err != nil {
+					errs <- fmt.Errorf("Get(1): %w", err)
+					return
+				}
+				if _, err := s.List(ctx); err != nil {
+					errs <- fmt.Errorf("List: %w", err)
+					return
+				}
+				if err := s.Delete(ctx, id); err != nil {
+					errs <- fmt.Errorf("Delete(%d): %w", id, err)
+					return
+				}
+				if _, err := s.Get(ctx, id); !errors.Is(err, ErrItemNotFound) {
+					errs <- fmt.Errorf("Get(%d) after Delete = %v, want ErrItemNotFound", id, err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if count, _ := s.Count(ctx); count != len(seedItems) {
+		t.Errorf("Count = %d, want %d", count, len(seedItems))
+	}
+}
@@ -68,9 +68,9 @@ export default function () {
   };
   res = http.post(`${BASE_URL}/items/`, payload, params);
   check(res, {
-    'item creation status is 200': (r) => r.status === 200,
-    // Safer check: Only parse body if status is 200 and body exists
-    'item created successfully': (r) => r.status === 200 && r.body && JSON.parse(r.body).message.includes('successfully'),
+    'item creation status is 201': (r) => r.status === 201,
+    // Safer check: Only parse body if status is 201 and body exists
+    'item created successfully': (r) => r.status === 201 && r.body && JSON.parse(r.body).message.includes('successfully'),
   }) || errorRate.add(1);
 
   // Occasionally trigger errors to test error handling
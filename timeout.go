@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// timeoutExemptPaths keep their own pace; a large scrape shouldn't be cut off mid-write, and
+// CPU profiles and execution traces run for as long as the caller asks
+var timeoutExemptPaths = map[string]bool{
+	"/metrics":             true,
+	"/debug/pprof/profile": true,
+	"/debug/pprof/trace":   true,
+}
+
+// timeoutMiddleware bounds each request with a context deadline, answering 503 when a handler
+// overruns it. Handlers are cut short only where they honour the request context (store queries,
+// outgoing HTTP calls); one that has already written its response keeps it.
+func timeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeoutExemptPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+
+		path := handlerLabel(c)
+		httpRequestTimeoutsTotal.WithLabelValues(c.Request.Method, path).Inc()
+
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.Bool("http.timeout", true))
+		span.SetStatus(codes.Error, "request timed out")
+
+		logger := getLogger(c)
+		if c.Writer.Written() {
+			logger.Warn("Request exceeded timeout after the response was written", "timeout", timeout.String())
+			return
+		}
+		logger.Warn("Request timed out", "timeout", timeout.String())
+		respondError(c, http.StatusServiceUnavailable, "timeout", "Request timed out")
+	}
+}